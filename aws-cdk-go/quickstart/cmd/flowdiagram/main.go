@@ -0,0 +1,71 @@
+// Command flowdiagram renders the action graph of a Contact Flow Module as Graphviz DOT and Mermaid diagrams, to
+// review changes to the flow module template or to the modules the stack builds from it.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/quickstart"
+	"github.com/iancoleman/orderedmap"
+)
+
+func main() {
+	modulePath := flag.String("module", "../../flow-modules/template/ASAPPGenerativeAgent.json", "Contact Flow Module to render, the template or a module written to staging/flow-modules by cdk synth")
+	format := flag.String("format", "both", "diagrams to render: dot, mermaid or both")
+	outDir := flag.String("out-dir", ".", "directory the <module>.dot and <module>.mmd diagrams are written to, - writes to stdout")
+	flag.Parse()
+
+	content, err := os.ReadFile(*modulePath)
+	if err != nil {
+		log.Fatalf("Failed to read Contact Flow Module: %v", err)
+	}
+	var module orderedmap.OrderedMap
+	if err := json.Unmarshal(content, &module); err != nil {
+		log.Fatalf("Failed to parse Contact Flow Module %s: %v", *modulePath, err)
+	}
+	if err := quickstart.ValidateModule(&module); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Contact Flow Module %s is not valid:\n%v\n", *modulePath, err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(*modulePath), filepath.Ext(*modulePath))
+	writeDOT := func(w io.Writer) error { return quickstart.WriteModuleDOT(w, &module) }
+	writeMermaid := func(w io.Writer) error { return quickstart.WriteModuleMermaid(w, &module) }
+	switch *format {
+	case "dot":
+		writeDiagram(*outDir, name+".dot", writeDOT)
+	case "mermaid":
+		writeDiagram(*outDir, name+".mmd", writeMermaid)
+	case "both":
+		writeDiagram(*outDir, name+".dot", writeDOT)
+		writeDiagram(*outDir, name+".mmd", writeMermaid)
+	default:
+		log.Fatalf("Unknown format %q, expected dot, mermaid or both", *format)
+	}
+}
+
+func writeDiagram(outDir, name string, write func(w io.Writer) error) {
+	if outDir == "-" {
+		if err := write(os.Stdout); err != nil {
+			log.Fatalf("Failed to render %s: %v", name, err)
+		}
+		return
+	}
+
+	path := filepath.Join(outDir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", path, err)
+	}
+	defer f.Close()
+	if err := write(f); err != nil {
+		log.Fatalf("Failed to render %s: %v", path, err)
+	}
+	fmt.Printf("Wrote %s\n", path)
+}
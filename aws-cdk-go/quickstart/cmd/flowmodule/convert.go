@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/quickstart"
+)
+
+// convert rewrites a flow module template made before the template had placeholders, such as the template of a fork,
+// to placeholders
+func convert(args []string) {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	templatePath := flags.String("template", defaultTemplatePath, "flow module template to convert")
+	out := flags.String("out", "", "file the converted template is written to, the -template file when empty")
+	flags.Parse(args)
+
+	if *out == "" {
+		*out = *templatePath
+	}
+	template, err := os.ReadFile(*templatePath)
+	if err != nil {
+		log.Fatalf("Failed to read flow module template: %v", err)
+	}
+	content, changes, err := quickstart.ConvertToPlaceholders(template)
+	if err != nil {
+		log.Fatalf("Failed to convert flow module template: %v", err)
+	}
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	if err := os.WriteFile(*out, content, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+	fmt.Printf("Wrote %s\n", *out)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/quickstart"
+)
+
+// extract turns a module exported from the Amazon Connect console back into a template and shows how it differs
+// from the current template
+func extract(args []string) {
+	flags := flag.NewFlagSet("extract", flag.ExitOnError)
+	modulePath := flags.String("module", "", "Contact Flow Module exported from the Amazon Connect console (required)")
+	templatePath := flags.String("template", defaultTemplatePath, "flow module template the placeholders are taken from and the module is compared to")
+	out := flags.String("out", "", "file the extracted template is written to, the -template file updates the template, empty only shows the diff")
+	flags.Parse(args)
+
+	if *modulePath == "" {
+		log.Fatalf("-module is required")
+	}
+	exported, err := os.ReadFile(*modulePath)
+	if err != nil {
+		log.Fatalf("Failed to read exported module: %v", err)
+	}
+	template, err := os.ReadFile(*templatePath)
+	if err != nil {
+		log.Fatalf("Failed to read flow module template: %v", err)
+	}
+
+	content, changes, warnings, err := quickstart.ExtractFlowModule(exported, template)
+	if err != nil {
+		log.Fatalf("Failed to extract template: %v", err)
+	}
+	for _, change := range changes {
+		fmt.Fprintln(os.Stderr, change)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	if err := quickstart.WriteUnifiedDiff(os.Stdout, *templatePath, *modulePath, template, content); err != nil {
+		log.Fatalf("Failed to write diff: %v", err)
+	}
+	if *out == "" {
+		return
+	}
+	if err := os.WriteFile(*out, content, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", *out)
+}
@@ -0,0 +1,39 @@
+// Command flowmodule builds the Contact Flow Module of a manual install, without the stack, with the same
+// transforms as the stack, turns modules edited in the Amazon Connect console back into the flow module template, and
+// converts templates made before the template had placeholders.
+//
+// Usage:
+//
+//	flowmodule render [flags]
+//	flowmodule extract [flags]
+//	flowmodule convert [flags]
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const defaultTemplatePath = "../../flow-modules/template/ASAPPGenerativeAgent.json"
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "render":
+		render(os.Args[2:])
+	case "extract":
+		extract(os.Args[2:])
+	case "convert":
+		convert(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: flowmodule render|extract|convert [flags]")
+	fmt.Fprintln(os.Stderr, "Run flowmodule <command> -h for the flags of a command")
+	os.Exit(2)
+}
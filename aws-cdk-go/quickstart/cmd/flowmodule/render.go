@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/quickstart"
+)
+
+// render writes the module built from the template for the given resources, ready to be imported in the Amazon
+// Connect console
+func render(args []string) {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	templatePath := flags.String("template", defaultTemplatePath, "flow module template")
+	out := flags.String("out", "my_ASAPPGenerativeAgent.json", "file the module is written to, - writes to stdout")
+	instanceArn := flags.String("connect-instance-arn", "", "ARN of the Amazon Connect instance (required)")
+	engageArn := flags.String("engage-lambda-arn", "", "ARN of the Engage Lambda function or alias (required)")
+	pullActionArn := flags.String("pullaction-lambda-arn", "", "ARN of the PullAction Lambda function or alias (required)")
+	wait1sPrompt := flags.String("silence-1s-prompt", "", "ID or ARN of the prompt uploaded from asappSilence1second.wav (required)")
+	wait400msPrompt := flags.String("silence-400ms-prompt", "", "ID or ARN of the prompt uploaded from asappSilence400ms.wav (required)")
+	beepBopPrompt := flags.String("beep-bop-prompt", "", "ID or ARN of the prompt uploaded from asappBeepBop.wav (required)")
+	outputVariables := flags.String("output-variables", "", `JSON object mapping output variables to contact attributes, e.g. {"customerId":"ASAPP_CustomerId"}`)
+	ssml := flags.Bool("ssml", false, "speak the responses as SSML, set when the PullAction function has SSML conversions")
+	moduleName := flags.String("module-name", "", "name of the module in the modules config of the Lambda functions, empty for the default module")
+	flags.Parse(args)
+
+	if *instanceArn == "" || *engageArn == "" || *pullActionArn == "" {
+		log.Fatalf("-connect-instance-arn, -engage-lambda-arn and -pullaction-lambda-arn are required")
+	}
+	if *wait1sPrompt == "" || *wait400msPrompt == "" || *beepBopPrompt == "" {
+		log.Fatalf("-silence-1s-prompt, -silence-400ms-prompt and -beep-bop-prompt are required")
+	}
+
+	module := config.ModuleConfig{Name: *moduleName}
+	if *outputVariables != "" {
+		if err := json.Unmarshal([]byte(*outputVariables), &module.OutputVariablesToAttributesMap); err != nil {
+			log.Fatalf("Invalid -output-variables: %v", err)
+		}
+	}
+	resources := quickstart.ModuleResources{
+		ConnectInstanceArn:  *instanceArn,
+		EngageLambdaArn:     *engageArn,
+		PullActionLambdaArn: *pullActionArn,
+		PromptIds: map[string]string{
+			"Wait1s":    *wait1sPrompt,
+			"Wait400ms": *wait400msPrompt,
+			"BeepBop":   *beepBopPrompt,
+		},
+	}
+
+	template, err := os.ReadFile(*templatePath)
+	if err != nil {
+		log.Fatalf("Failed to read flow module template: %v", err)
+	}
+	content, reports, err := quickstart.RenderFlowModule(template, module, resources, *ssml)
+	if err != nil {
+		log.Fatalf("Failed to render Contact Flow Module: %v", err)
+	}
+
+	// Keep stdout for the module when it is written there
+	report := io.Writer(os.Stdout)
+	if *out == "-" {
+		report = os.Stderr
+	}
+	for _, r := range reports {
+		for _, change := range r.Changes {
+			fmt.Fprintf(report, "%s: %s\n", r.Transform, change)
+		}
+	}
+
+	if *out == "-" {
+		os.Stdout.Write(append(content, '\n'))
+		return
+	}
+	if err := os.WriteFile(*out, append(content, '\n'), 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+	fmt.Printf("Wrote %s\n", *out)
+}
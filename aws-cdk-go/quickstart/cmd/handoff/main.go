@@ -0,0 +1,65 @@
+// Command handoff renders the values ASAPP needs from a deployment, read from the result of
+// `cdk deploy --outputs-file`, into JSON and Markdown documents.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/handoff"
+)
+
+func main() {
+	outputsFile := flag.String("outputs-file", "cdk-outputs.json", "file written by cdk deploy --outputs-file")
+	stackName := flag.String("stack", "", "stack to read outputs for, required when the outputs file contains more than one stack")
+	format := flag.String("format", "both", "documents to render: json, markdown or both")
+	outDir := flag.String("out-dir", ".", "directory the asapp-handoff.json and asapp-handoff.md documents are written to, - writes to stdout")
+	flag.Parse()
+
+	f, err := os.Open(*outputsFile)
+	if err != nil {
+		log.Fatalf("Failed to open outputs file: %v", err)
+	}
+	defer f.Close()
+
+	doc, err := handoff.Load(f, *stackName)
+	if err != nil {
+		log.Fatalf("Failed to load deployment outputs: %v", err)
+	}
+
+	switch *format {
+	case "json":
+		writeDocument(*outDir, "asapp-handoff.json", doc.WriteJSON)
+	case "markdown":
+		writeDocument(*outDir, "asapp-handoff.md", doc.WriteMarkdown)
+	case "both":
+		writeDocument(*outDir, "asapp-handoff.json", doc.WriteJSON)
+		writeDocument(*outDir, "asapp-handoff.md", doc.WriteMarkdown)
+	default:
+		log.Fatalf("Unknown format %q, expected json, markdown or both", *format)
+	}
+}
+
+func writeDocument(outDir, name string, write func(w io.Writer) error) {
+	if outDir == "-" {
+		if err := write(os.Stdout); err != nil {
+			log.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return
+	}
+
+	path := filepath.Join(outDir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", path, err)
+	}
+	defer f.Close()
+	if err := write(f); err != nil {
+		log.Fatalf("Failed to write %s: %v", path, err)
+	}
+	fmt.Printf("Wrote %s\n", path)
+}
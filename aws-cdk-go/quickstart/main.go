@@ -24,6 +24,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error loading config: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
 	jsonConfig, _ := json.MarshalIndent(cfg, "", "  ")
 	fmt.Printf("Loaded configuration:\n%+v\n", string(jsonConfig))
 
@@ -31,8 +34,10 @@ func main() {
 
 	quickstart.NewQuickStartGenerativeAgentStack(app, fmt.Sprintf("%sstack", cfg.ObjectPrefix), &quickstart.AmazonConnectDemoCdkStackProps{
 		StackProps: awscdk.StackProps{
-			Env: env(cfg.AccountId, cfg.Region),
+			Env:                   env(cfg.AccountId, cfg.Region),
+			TerminationProtection: jsii.Bool(cfg.TerminationProtection),
 		},
+		EnvName: jsii.String(envName),
 	}, cfg)
 
 	app.Synth(nil)
@@ -1,10 +1,17 @@
 package config
 
+import "strings"
+
 type AsappConfig struct { // Asapp provided variables
 	ApiHost         string `config:"asapp-apiHost,required"`
 	ApiId           string `config:"asapp-apiId,required"`
 	ApiSecret       string `config:"asapp-apiSecret,required"`
 	AssumingRoleArn string `config:"asapp-assumingRoleArn,required"`
+
+	// Trust policy restrictions applied to the role ASAPP assumes
+	ExternalId                string   `config:"asapp-externalId"`
+	AllowedSourceIps          []string `config:"asapp-allowedSourceIps"`
+	MaxSessionDurationSeconds int      `config:"asapp-maxSessionDurationSeconds"`
 }
 
 type ValkeyParameters struct { // Valkey configuration parameters
@@ -19,18 +26,238 @@ type Config struct {
 	ObjectPrefix       string `config:"objectPrefix"`
 	UseExistingVpcId   string `config:"useExistingVpcId"`
 
+	Tags map[string]string `config:"tags"`
+
+	RetainOnDelete        bool                  `config:"retainOnDelete"`
+	RemovalPolicies       RemovalPoliciesConfig `config:"removalPolicies"`
+	TerminationProtection bool                  `config:"terminationProtection"`
+
+	PromptBucket PromptBucketConfig `config:"promptBucket"`
+
+	SsmParameters SsmParametersConfig `config:"ssmParameters"`
+	AppConfig     AppConfigConfig     `config:"appConfig"`
+
+	Engage EngageConfig `config:"engage"`
+
+	TextToSpeech          TextToSpeechConfig          `config:"textToSpeech"`
+	TransferToAgentQueues TransferToAgentQueuesConfig `config:"transferToAgentQueues"`
+	SampleContactFlow     SampleContactFlowConfig     `config:"sampleContactFlow"`
+	PhoneNumbers          PhoneNumbersConfig          `config:"phoneNumbers"`
+
 	AttributesToInputVariablesMap  map[string]string `config:"attributesToInputVariablesMap"`
+	InputVariables                 []InputVariable   `config:"inputVariables"`
 	OutputVariablesToAttributesMap map[string]string `config:"outputVariablesToAttributesMap"`
 	SSMLConversions                []SSMLConversion  `config:"ssmlConversions"`
+	Lexicons                       []Lexicon         `config:"lexicons"`
+	SSMLPreviewSamples             []string          `config:"ssmlPreviewSamples"`
+
+	Modules []ModuleConfig `config:"modules"`
+
+	FlowModuleArns FlowModuleArnsConfig `config:"flowModuleArns"`
 
 	Asapp                        AsappConfig
 	ValkeyParameters             ValkeyParameters
 	LambdaProvisionedConcurrency LambdaProvisionedConcurencyConfig `config:"lambdaProvisionedConcurrency"`
 }
 
+// Removal policy values accepted in RemovalPoliciesConfig
+const (
+	RemovalPolicyDestroy  = "destroy"
+	RemovalPolicySnapshot = "snapshot"
+	RemovalPolicyRetain   = "retain"
+)
+
+type RemovalPoliciesConfig struct { // Per-resource overrides of retainOnDelete, empty means follow retainOnDelete
+	PromptBucket         string `config:"promptBucket"`
+	LogGroups            string `config:"logGroups"`
+	Valkey               string `config:"valkey"`
+	KinesisStorageConfig string `config:"kinesisStorageConfig"`
+}
+
+type PromptBucketConfig struct { // Hardening of the S3 bucket that stores the prompt audio files
+	DisableHardening     bool   `config:"disableHardening"`
+	KmsKeyArn            string `config:"kmsKeyArn"`
+	AccessLogsBucketName string `config:"accessLogsBucketName"`
+	AccessLogsPrefix     string `config:"accessLogsPrefix"`
+}
+
+type SsmParametersConfig struct { // Publishes the stack outputs as SSM parameters
+	Enabled    bool   `config:"enabled"`
+	PathPrefix string `config:"pathPrefix"`
+}
+
+// SsmParameterPath returns the path the SSM parameters are created under, derived from objectPrefix unless
+// ssmParameters.pathPrefix is set.
+func (c *Config) SsmParameterPath() string {
+	path := c.SsmParameters.PathPrefix
+	if path == "" {
+		path = "/" + strings.TrimSuffix(c.ObjectPrefix, "-")
+	}
+	return strings.TrimSuffix(path, "/") + "/"
+}
+
+type TextToSpeechConfig struct { // Voice set by the Contact Flow Module, the voice of the invoking flow is used when empty
+	Voice    string `config:"voice"`
+	Engine   string `config:"engine"`
+	Language string `config:"language"`
+}
+
+type TransferToAgentQueuesConfig struct { // Sets the queue of transferToAgent dispositions from the Contact Flow Module
+	OutputVariable  string            `config:"outputVariable"`
+	QueueArns       map[string]string `config:"queueArns"`
+	DefaultQueueArn string            `config:"defaultQueueArn"`
+}
+
+// Enabled reports whether the Contact Flow Module sets the queue of transferToAgent dispositions
+func (t *TransferToAgentQueuesConfig) Enabled() bool {
+	return t.DefaultQueueArn != "" || len(t.QueueArns) > 0
+}
+
+// DefaultRelocatedArns are the services and resource types of the ARNs written in the flow module template that are
+// moved to the partition, region and account of the deployment when flowModuleArns.relocate is not set
+var DefaultRelocatedArns = []string{"lambda:function"}
+
+type FlowModuleArnsConfig struct { // Selects the ARNs written in the flow module template that belong to the deployment
+	// Relocate lists services, e.g. lambda, or service:resourceType pairs, e.g. lambda:function, whose ARNs are moved
+	// to the partition, region and account of the deployment. The other ARNs are left as they are.
+	Relocate []string `config:"relocate"`
+}
+
+// RelocatedArns returns the services and resource types whose ARNs are relocated
+func (f *FlowModuleArnsConfig) RelocatedArns() []string {
+	if f.Relocate == nil {
+		return DefaultRelocatedArns
+	}
+	return f.Relocate
+}
+
+type EngageConfig struct { // Fields of the engage request sent to GenerativeAgent
+	Language         string `config:"language"`
+	FallbackLanguage string `config:"fallbackLanguage"`
+	Namespace        string `config:"namespace"`
+	CustomerIdSource string `config:"customerIdSource"`
+}
+
+// EngageLanguageContact makes the engage request use the language of the contact
+const EngageLanguageContact = "contact"
+
+type AppConfigConfig struct { // Lets the Lambda functions read SSML conversions and attribute maps from AWS AppConfig at runtime
+	Enabled              bool   `config:"enabled"`
+	DeploymentStrategyId string `config:"deploymentStrategyId"`
+	PollIntervalSeconds  int    `config:"pollIntervalSeconds"`
+	SkipVpcEndpoint      bool   `config:"skipVpcEndpoint"`
+}
+
+type SampleContactFlowConfig struct { // Optional inbound contact flow that invokes the Contact Flow Module
+	Enabled         bool   `config:"enabled"`
+	CompanyMarker   string `config:"companyMarker"`
+	QueueArn        string `config:"queueArn"`
+	FallbackFlowArn string `config:"fallbackFlowArn"`
+}
+
+type PhoneNumbersConfig struct { // Phone numbers associated with the sample contact flow, or with contactFlowArn if set
+	Arns           []string               `config:"arns"`
+	Claim          PhoneNumberClaimConfig `config:"claim"`
+	ContactFlowArn string                 `config:"contactFlowArn"`
+}
+
+type PhoneNumberClaimConfig struct { // Claims a new phone number for the instance, meant for sandbox accounts
+	CountryCode string `config:"countryCode"`
+	Type        string `config:"type"`
+	Prefix      string `config:"prefix"`
+}
+
 type SSMLConversion struct {
 	SearchFor   string `json:"searchFor"`
 	ReplaceWith string `json:"replaceWith"`
+	// ReplaceGroups replaces a match with the value of the named group of SearchFor that took part in it, instead of
+	// ReplaceWith. Values only expand $& and $$. Lexicons are applied in a single pass this way.
+	ReplaceGroups map[string]string `json:"replaceGroups,omitempty"`
+}
+
+// InputVariable maps a value of the Amazon Connect Lambda event to a GenerativeAgent input variable. Source is a dot
+// separated path under Details, e.g. ContactData.Channel or ContactData.Attributes.tier.
+type InputVariable struct {
+	Source    string  `json:"source"`
+	Target    string  `json:"target"`
+	Default   *string `json:"default"`
+	Required  bool    `json:"required"`
+	Transform string  `json:"transform"`
+	Type      string  `json:"type"`
+}
+
+// Transforms and types of input variables
+const (
+	InputVariableTransformLowercase = "lowercase"
+	InputVariableTransformUppercase = "uppercase"
+	InputVariableTransformTrim      = "trim"
+	InputVariableTransformE164      = "e164"
+
+	InputVariableTypeString  = "string"
+	InputVariableTypeNumber  = "number"
+	InputVariableTypeBoolean = "boolean"
+)
+
+// Lexicon is an Amazon Polly pronunciation lexicon, see https://docs.aws.amazon.com/polly/latest/dg/managing-lexicons.html
+type Lexicon struct {
+	Name     string   `json:"name"`
+	Language string   `json:"language"`
+	Alphabet string   `json:"alphabet"`
+	Lexemes  []Lexeme `json:"lexemes"`
+}
+
+// Lexeme is pronounced either with Phoneme, written in the alphabet of the lexicon, or as Alias
+type Lexeme struct {
+	Graphemes []string `json:"graphemes"`
+	Phoneme   string   `json:"phoneme"`
+	Alias     string   `json:"alias"`
+}
+
+// ModuleConfig is a Contact Flow Module created from the flow module template. All modules share the Lambda
+// functions and Valkey, fields that are not set inherit the top-level value of the same name.
+type ModuleConfig struct {
+	Name                           string                       `json:"name"`
+	AttributesToInputVariablesMap  map[string]string            `json:"attributesToInputVariablesMap"`
+	InputVariables                 []InputVariable              `json:"inputVariables"`
+	OutputVariablesToAttributesMap map[string]string            `json:"outputVariablesToAttributesMap"`
+	SSMLConversions                []SSMLConversion             `json:"ssmlConversions"`
+	TextToSpeech                   *TextToSpeechConfig          `json:"textToSpeech"`
+	TransferToAgentQueues          *TransferToAgentQueuesConfig `json:"transferToAgentQueues"`
+}
+
+// FlowModules returns the Contact Flow Modules of the deployment with the inherited values filled in. Without
+// modules, the deployment has a single unnamed module built from the top-level values.
+func (c *Config) FlowModules() []ModuleConfig {
+	if len(c.Modules) == 0 {
+		return []ModuleConfig{c.inheritModule(ModuleConfig{})}
+	}
+	modules := make([]ModuleConfig, 0, len(c.Modules))
+	for _, module := range c.Modules {
+		modules = append(modules, c.inheritModule(module))
+	}
+	return modules
+}
+
+func (c *Config) inheritModule(module ModuleConfig) ModuleConfig {
+	if module.AttributesToInputVariablesMap == nil {
+		module.AttributesToInputVariablesMap = c.AttributesToInputVariablesMap
+	}
+	if module.InputVariables == nil {
+		module.InputVariables = c.InputVariables
+	}
+	if module.OutputVariablesToAttributesMap == nil {
+		module.OutputVariablesToAttributesMap = c.OutputVariablesToAttributesMap
+	}
+	if module.SSMLConversions == nil {
+		module.SSMLConversions = c.SSMLConversions
+	}
+	if module.TextToSpeech == nil {
+		module.TextToSpeech = &c.TextToSpeech
+	}
+	if module.TransferToAgentQueues == nil {
+		module.TransferToAgentQueues = &c.TransferToAgentQueues
+	}
+	return module
 }
 
 type LambdaProvisionedConcurencyConfig struct {
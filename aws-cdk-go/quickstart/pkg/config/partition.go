@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// AWS partitions the quickstart can be deployed to, matching the target partitions of cdk.json
+const (
+	PartitionAws      = "aws"
+	PartitionAwsCn    = "aws-cn"
+	PartitionAwsUsGov = "aws-us-gov"
+)
+
+// Partition returns the AWS partition of the deployment, taken from connectInstanceArn. It falls back to the
+// partition of the region when connectInstanceArn is not a valid ARN.
+func (c *Config) Partition() string {
+	parsed, err := arn.Parse(c.ConnectInstanceArn)
+	if err != nil {
+		return RegionPartition(c.Region)
+	}
+	return parsed.Partition
+}
+
+// RegionPartition returns the AWS partition a region belongs to, e.g. aws-cn for cn-north-1
+func RegionPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return PartitionAwsCn
+	case strings.HasPrefix(region, "us-gov-"):
+		return PartitionAwsUsGov
+	default:
+		return PartitionAws
+	}
+}
+
+// validatePartition checks that connectInstanceArn is the ARN of an Amazon Connect instance in a supported partition,
+// and that the region of the deployment belongs to that partition
+func (c *Config) validatePartition() error {
+	parsed, err := arn.Parse(c.ConnectInstanceArn)
+	if err != nil {
+		return fmt.Errorf("connectInstanceArn: %q is not a valid ARN: %w", c.ConnectInstanceArn, err)
+	}
+	if parsed.Service != "connect" || !strings.HasPrefix(parsed.Resource, "instance/") {
+		return fmt.Errorf("connectInstanceArn: %q is not the ARN of an Amazon Connect instance", c.ConnectInstanceArn)
+	}
+	return ValidateRegionPartition(c.Region, parsed.Partition)
+}
+
+// ValidateRegionPartition checks that partition is supported and that region belongs to it
+func ValidateRegionPartition(region, partition string) error {
+	switch partition {
+	case PartitionAws, PartitionAwsCn, PartitionAwsUsGov:
+	default:
+		return fmt.Errorf("partition %s is not supported, expected %s, %s or %s", partition, PartitionAws, PartitionAwsCn, PartitionAwsUsGov)
+	}
+	if RegionPartition(region) != partition {
+		return fmt.Errorf("region %s does not belong to the %s partition of connectInstanceArn", region, partition)
+	}
+	return nil
+}
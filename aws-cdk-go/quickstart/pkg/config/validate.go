@@ -0,0 +1,488 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// Validate checks configuration values that can be verified before any AWS resources are synthesized.
+func (c *Config) Validate() error {
+	if err := c.validatePartition(); err != nil {
+		return err
+	}
+	if err := c.Asapp.validate(); err != nil {
+		return err
+	}
+	if err := validateTags(c.Tags); err != nil {
+		return err
+	}
+	if err := c.RemovalPolicies.validate(); err != nil {
+		return err
+	}
+	if err := c.PromptBucket.validate(); err != nil {
+		return err
+	}
+	if c.SsmParameters.Enabled {
+		if err := validateSsmParameterPath(c.SsmParameterPath()); err != nil {
+			return err
+		}
+	}
+	if err := c.Engage.validate(); err != nil {
+		return err
+	}
+	if err := validateInputVariables(c.InputVariables); err != nil {
+		return err
+	}
+	if err := validateLexicons(c.Lexicons); err != nil {
+		return err
+	}
+	if err := c.TextToSpeech.validate(); err != nil {
+		return err
+	}
+	if err := c.TransferToAgentQueues.validate(); err != nil {
+		return err
+	}
+	if err := validateModules(c.Modules); err != nil {
+		return err
+	}
+	if err := c.FlowModuleArns.validate(); err != nil {
+		return err
+	}
+	if c.AppConfig.PollIntervalSeconds < 0 || c.AppConfig.PollIntervalSeconds > 3600 {
+		return fmt.Errorf("appConfig.pollIntervalSeconds: %d is not between 0 and 3600", c.AppConfig.PollIntervalSeconds)
+	}
+	if c.SampleContactFlow.Enabled {
+		if err := c.SampleContactFlow.validate(); err != nil {
+			return err
+		}
+		// The sample contact flow invokes the first module
+		if c.SampleContactFlow.QueueArn != "" && c.FlowModules()[0].TransferToAgentQueues.Enabled() {
+			return fmt.Errorf("sampleContactFlow.queueArn cannot be combined with transferToAgentQueues, use transferToAgentQueues.defaultQueueArn instead")
+		}
+	}
+	if err := c.validatePhoneNumbers(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Module names are part of construct IDs, Contact Flow Module names and stack output keys
+var moduleNamePattern = regexp.MustCompile(`^[a-zA-Z0-9]{1,40}$`)
+
+func validateModules(modules []ModuleConfig) error {
+	names := map[string]bool{}
+	for i, module := range modules {
+		if !moduleNamePattern.MatchString(module.Name) {
+			return fmt.Errorf("modules[%d].name: %q must be 1 to 40 letters or digits", i, module.Name)
+		}
+		// Stack output keys are lowercase
+		if names[strings.ToLower(module.Name)] {
+			return fmt.Errorf("modules[%d].name: %q is used by more than one module", i, module.Name)
+		}
+		names[strings.ToLower(module.Name)] = true
+		if err := validateInputVariables(module.InputVariables); err != nil {
+			return fmt.Errorf("modules[%d].%v", i, err)
+		}
+		if module.TextToSpeech != nil {
+			if err := module.TextToSpeech.validate(); err != nil {
+				return fmt.Errorf("modules[%d].%v", i, err)
+			}
+		}
+		if module.TransferToAgentQueues != nil {
+			if err := module.TransferToAgentQueues.validate(); err != nil {
+				return fmt.Errorf("modules[%d].%v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+var countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+func (c *Config) validatePhoneNumbers() error {
+	p := c.PhoneNumbers
+	phoneNumberIds := map[string]bool{}
+	for _, phoneNumberArn := range p.Arns {
+		parsed, err := arn.Parse(phoneNumberArn)
+		if err != nil || parsed.Service != "connect" || !strings.HasPrefix(parsed.Resource, "phone-number/") {
+			return fmt.Errorf("phoneNumbers.arns: %q is not an Amazon Connect phone number ARN", phoneNumberArn)
+		}
+		// Each number has a single association, named by the phone number ID
+		phoneNumberId := strings.TrimPrefix(parsed.Resource, "phone-number/")
+		if phoneNumberIds[phoneNumberId] {
+			return fmt.Errorf("phoneNumbers.arns: phone number %s is listed more than once", phoneNumberId)
+		}
+		phoneNumberIds[phoneNumberId] = true
+	}
+	if p.Claim.CountryCode != "" {
+		if !countryCodePattern.MatchString(p.Claim.CountryCode) {
+			return fmt.Errorf("phoneNumbers.claim.countryCode: %q is not an ISO country code, e.g. US", p.Claim.CountryCode)
+		}
+		if !slices.Contains([]string{"DID", "TOLL_FREE"}, p.Claim.Type) {
+			return fmt.Errorf("phoneNumbers.claim.type must be DID or TOLL_FREE, got %q", p.Claim.Type)
+		}
+	} else if p.Claim.Type != "" || p.Claim.Prefix != "" {
+		return fmt.Errorf("phoneNumbers.claim.countryCode is required to claim a phone number")
+	}
+	if len(p.Arns) == 0 && p.Claim.CountryCode == "" {
+		return nil
+	}
+	if p.ContactFlowArn != "" {
+		if !isConnectArn(p.ContactFlowArn, "contact-flow") {
+			return fmt.Errorf("phoneNumbers.contactFlowArn: %q is not an Amazon Connect contact flow ARN", p.ContactFlowArn)
+		}
+	} else if !c.SampleContactFlow.Enabled {
+		return fmt.Errorf("phoneNumbers require either phoneNumbers.contactFlowArn or sampleContactFlow.enabled")
+	}
+	return nil
+}
+
+// Fields of the ContactData object of the Amazon Connect Lambda event, with the fields of the nested objects that
+// can be used as input variable sources. A nil list means any field of the object can be used.
+// See https://docs.aws.amazon.com/connect/latest/adminguide/connect-lambda-functions.html
+var contactDataFields = map[string][]string{
+	"Attributes":        nil,
+	"SegmentAttributes": nil,
+	"Tags":              nil,
+	"Channel":           {},
+	"ContactId":         {},
+	"InitialContactId":  {},
+	"PreviousContactId": {},
+	"InitiationMethod":  {},
+	"InstanceARN":       {},
+	"LanguageCode":      {},
+	"Name":              {},
+	"Description":       {},
+	"CustomerId":        {},
+	"CustomerEndpoint":  {"Address", "Type"},
+	"SystemEndpoint":    {"Address", "Type"},
+	"Queue":             {"ARN", "Name", "OutboundCallerId"},
+}
+
+func validateInputVariables(inputVariables []InputVariable) error {
+	targets := map[string]bool{}
+	for i, inputVariable := range inputVariables {
+		if err := validateInputVariableSource(inputVariable.Source); err != nil {
+			return fmt.Errorf("inputVariables[%d].source: %v", i, err)
+		}
+		if inputVariable.Target == "" {
+			return fmt.Errorf("inputVariables[%d].target is required", i)
+		}
+		if targets[inputVariable.Target] {
+			return fmt.Errorf("inputVariables[%d].target: %q is mapped more than once", i, inputVariable.Target)
+		}
+		targets[inputVariable.Target] = true
+		if !slices.Contains([]string{"", InputVariableTransformLowercase, InputVariableTransformUppercase, InputVariableTransformTrim, InputVariableTransformE164}, inputVariable.Transform) {
+			return fmt.Errorf("inputVariables[%d].transform: %q is not valid, expected %s, %s, %s or %s", i, inputVariable.Transform,
+				InputVariableTransformLowercase, InputVariableTransformUppercase, InputVariableTransformTrim, InputVariableTransformE164)
+		}
+		if !slices.Contains([]string{"", InputVariableTypeString, InputVariableTypeNumber, InputVariableTypeBoolean}, inputVariable.Type) {
+			return fmt.Errorf("inputVariables[%d].type: %q is not valid, expected %s, %s or %s", i, inputVariable.Type,
+				InputVariableTypeString, InputVariableTypeNumber, InputVariableTypeBoolean)
+		}
+		if inputVariable.Default != nil {
+			if err := validateInputVariableDefault(*inputVariable.Default, inputVariable.Type); err != nil {
+				return fmt.Errorf("inputVariables[%d].default: %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *EngageConfig) validate() error {
+	if e.Language != "" && e.Language != EngageLanguageContact && !languageCodePattern.MatchString(e.Language) {
+		return fmt.Errorf("engage.language: %q is not a language code such as en-US, or %s", e.Language, EngageLanguageContact)
+	}
+	if e.FallbackLanguage != "" {
+		if e.Language != EngageLanguageContact {
+			return fmt.Errorf("engage.fallbackLanguage is only used when engage.language is %s", EngageLanguageContact)
+		}
+		if !languageCodePattern.MatchString(e.FallbackLanguage) {
+			return fmt.Errorf("engage.fallbackLanguage: %q is not a language code such as en-US", e.FallbackLanguage)
+		}
+	}
+	if e.CustomerIdSource != "" {
+		if err := validateInputVariableSource(e.CustomerIdSource); err != nil {
+			return fmt.Errorf("engage.customerIdSource: %v", err)
+		}
+	}
+	return nil
+}
+
+func validateInputVariableSource(source string) error {
+	path := strings.Split(source, ".")
+	if slices.Contains(path, "") {
+		return fmt.Errorf("%q is not a dot separated path", source)
+	}
+	switch path[0] {
+	case "Parameters":
+		if len(path) != 2 {
+			return fmt.Errorf("%q must name a single parameter of the Lambda invocation, e.g. Parameters.name", source)
+		}
+		return nil
+	case "ContactData":
+		if len(path) < 2 {
+			return fmt.Errorf("%q must name a field of ContactData", source)
+		}
+		fields, ok := contactDataFields[path[1]]
+		if !ok {
+			return fmt.Errorf("%q: %s is not a field of ContactData", source, path[1])
+		}
+		if fields == nil {
+			if len(path) != 3 {
+				return fmt.Errorf("%q must name a single entry of ContactData.%s", source, path[1])
+			}
+			return nil
+		}
+		if len(fields) == 0 {
+			if len(path) != 2 {
+				return fmt.Errorf("%q: ContactData.%s has no fields", source, path[1])
+			}
+			return nil
+		}
+		if len(path) != 3 || !slices.Contains(fields, path[2]) {
+			return fmt.Errorf("%q: ContactData.%s fields are %s", source, path[1], strings.Join(fields, ", "))
+		}
+		return nil
+	default:
+		return fmt.Errorf("%q must start with ContactData or Parameters", source)
+	}
+}
+
+func validateInputVariableDefault(value, valueType string) error {
+	switch valueType {
+	case InputVariableTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+	case InputVariableTypeBoolean:
+		if !slices.Contains([]string{"true", "false"}, value) {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+	}
+	return nil
+}
+
+// LexiconNameHashLength is the length of the hash of objectPrefix appended to the name of lexicons in Amazon Polly,
+// whose names are at most 20 letters or digits
+const LexiconNameHashLength = 8
+
+var (
+	lexiconNamePattern  = regexp.MustCompile(`^[0-9A-Za-z]{1,12}$`)
+	languageCodePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+)
+
+func validateLexicons(lexicons []Lexicon) error {
+	names := map[string]bool{}
+	// Graphemes are matched case-insensitively, a grapheme can only have one pronunciation per language
+	graphemes := map[string]bool{}
+	for i, lexicon := range lexicons {
+		if !lexiconNamePattern.MatchString(lexicon.Name) {
+			return fmt.Errorf("lexicons[%d].name: %q must be 1 to 12 letters or digits, Amazon Polly names are at most 20 with the %d characters added to the name", i, lexicon.Name, LexiconNameHashLength)
+		}
+		if names[lexicon.Name] {
+			return fmt.Errorf("lexicons[%d].name: %q is used by more than one lexicon", i, lexicon.Name)
+		}
+		names[lexicon.Name] = true
+		if !languageCodePattern.MatchString(lexicon.Language) {
+			return fmt.Errorf("lexicons[%d].language: %q is not a language code such as en-US", i, lexicon.Language)
+		}
+		if lexicon.Alphabet != "ipa" && lexicon.Alphabet != "x-sampa" {
+			return fmt.Errorf("lexicons[%d].alphabet: %q is not valid, expected ipa or x-sampa", i, lexicon.Alphabet)
+		}
+		if len(lexicon.Lexemes) == 0 {
+			return fmt.Errorf("lexicons[%d].lexemes: at least one lexeme is required", i)
+		}
+		for j, lexeme := range lexicon.Lexemes {
+			if len(lexeme.Graphemes) == 0 {
+				return fmt.Errorf("lexicons[%d].lexemes[%d].graphemes: at least one grapheme is required", i, j)
+			}
+			for _, grapheme := range lexeme.Graphemes {
+				if strings.TrimSpace(grapheme) == "" {
+					return fmt.Errorf("lexicons[%d].lexemes[%d].graphemes: graphemes must not be blank", i, j)
+				}
+				key := strings.ToLower(lexicon.Language) + "/" + strings.ToLower(grapheme)
+				if graphemes[key] {
+					return fmt.Errorf("lexicons[%d].lexemes[%d].graphemes: %q has more than one pronunciation in %s", i, j, grapheme, lexicon.Language)
+				}
+				graphemes[key] = true
+			}
+			if (lexeme.Phoneme == "") == (lexeme.Alias == "") {
+				return fmt.Errorf("lexicons[%d].lexemes[%d]: exactly one of phoneme or alias is required", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+func (t *TextToSpeechConfig) validate() error {
+	if t.Voice == "" {
+		if t.Engine != "" || t.Language != "" {
+			return fmt.Errorf("textToSpeech.voice is required when textToSpeech.engine or textToSpeech.language is set")
+		}
+		return nil
+	}
+	voice, ok := pollyVoices[t.Voice]
+	if !ok {
+		return fmt.Errorf("textToSpeech.voice: %q is not a known Amazon Polly voice ID", t.Voice)
+	}
+	if !slices.Contains([]string{TextToSpeechEngineStandard, TextToSpeechEngineNeural, TextToSpeechEngineGenerative}, t.Engine) {
+		return fmt.Errorf("textToSpeech.engine: %q is not valid, expected %s, %s or %s", t.Engine, TextToSpeechEngineStandard, TextToSpeechEngineNeural, TextToSpeechEngineGenerative)
+	}
+	if !slices.Contains(voice.engines, t.Engine) {
+		return fmt.Errorf("textToSpeech.engine: voice %s does not support the %s engine, supported engines are %s", t.Voice, t.Engine, strings.Join(voice.engines, ", "))
+	}
+	if t.Language != "" && !slices.Contains(voice.languages, t.Language) {
+		return fmt.Errorf("textToSpeech.language: voice %s does not speak %s, supported languages are %s", t.Voice, t.Language, strings.Join(voice.languages, ", "))
+	}
+	return nil
+}
+
+var outputVariablePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+func (t *TransferToAgentQueuesConfig) validate() error {
+	if len(t.QueueArns) > 0 && t.OutputVariable == "" {
+		return fmt.Errorf("transferToAgentQueues.outputVariable is required when transferToAgentQueues.queueArns is set")
+	}
+	if t.OutputVariable != "" && !outputVariablePattern.MatchString(t.OutputVariable) {
+		return fmt.Errorf("transferToAgentQueues.outputVariable: %q may only contain letters, digits and underscores", t.OutputVariable)
+	}
+	for value, queueArn := range t.QueueArns {
+		if !isConnectArn(queueArn, "queue") {
+			return fmt.Errorf("transferToAgentQueues.queueArns[%q]: %q is not an Amazon Connect queue ARN", value, queueArn)
+		}
+	}
+	if t.DefaultQueueArn != "" && !isConnectArn(t.DefaultQueueArn, "queue") {
+		return fmt.Errorf("transferToAgentQueues.defaultQueueArn: %q is not an Amazon Connect queue ARN", t.DefaultQueueArn)
+	}
+	return nil
+}
+
+func (f *SampleContactFlowConfig) validate() error {
+	if f.CompanyMarker == "" {
+		return fmt.Errorf("sampleContactFlow.companyMarker is required when the sample contact flow is enabled")
+	}
+	if f.QueueArn != "" && !isConnectArn(f.QueueArn, "queue") {
+		return fmt.Errorf("sampleContactFlow.queueArn: %q is not an Amazon Connect queue ARN", f.QueueArn)
+	}
+	if f.FallbackFlowArn != "" && !isConnectArn(f.FallbackFlowArn, "contact-flow") {
+		return fmt.Errorf("sampleContactFlow.fallbackFlowArn: %q is not an Amazon Connect contact flow ARN", f.FallbackFlowArn)
+	}
+	return nil
+}
+
+// relocatedArnPattern matches a service, optionally followed by a resource type, as they appear in ARNs
+var relocatedArnPattern = regexp.MustCompile(`^[a-z0-9-]+(:[a-zA-Z0-9-]+)?$`)
+
+func (f *FlowModuleArnsConfig) validate() error {
+	for i, resource := range f.Relocate {
+		if !relocatedArnPattern.MatchString(resource) {
+			return fmt.Errorf("flowModuleArns.relocate[%d]: %q is not a service or a service:resourceType pair, e.g. lambda:function", i, resource)
+		}
+	}
+	return nil
+}
+
+// isConnectArn reports whether value is an ARN of an Amazon Connect instance resource of the given type, e.g.
+// arn:aws:connect:region:account:instance/instance-id/queue/queue-id
+func isConnectArn(value, resourceType string) bool {
+	parsed, err := arn.Parse(value)
+	if err != nil || parsed.Service != "connect" {
+		return false
+	}
+	sections := strings.Split(parsed.Resource, "/")
+	return len(sections) == 4 && sections[0] == "instance" && sections[2] == resourceType
+}
+
+var ssmParameterPathPattern = regexp.MustCompile(`^(/[a-zA-Z0-9_.-]+)+/$`)
+
+func validateSsmParameterPath(path string) error {
+	if !ssmParameterPathPattern.MatchString(path) {
+		return fmt.Errorf("ssmParameters: %q is not a valid parameter path, it must start with / and contain only letters, numbers, and the characters ./_-", path)
+	}
+	lower := strings.ToLower(path)
+	if strings.HasPrefix(lower, "/aws") || strings.HasPrefix(lower, "/ssm") {
+		return fmt.Errorf("ssmParameters: %q uses a reserved aws or ssm prefix", path)
+	}
+	return nil
+}
+
+func (p *PromptBucketConfig) validate() error {
+	if p.DisableHardening && (p.KmsKeyArn != "" || p.AccessLogsBucketName != "") {
+		return fmt.Errorf("promptBucket.kmsKeyArn and promptBucket.accessLogsBucketName require hardening, remove promptBucket.disableHardening")
+	}
+	if p.KmsKeyArn != "" {
+		keyArn, err := arn.Parse(p.KmsKeyArn)
+		if err != nil || keyArn.Service != "kms" {
+			return fmt.Errorf("promptBucket.kmsKeyArn: %q is not a KMS key ARN", p.KmsKeyArn)
+		}
+	}
+	if p.AccessLogsPrefix != "" && p.AccessLogsBucketName == "" {
+		return fmt.Errorf("promptBucket.accessLogsPrefix requires promptBucket.accessLogsBucketName")
+	}
+	return nil
+}
+
+func (r *RemovalPoliciesConfig) validate() error {
+	retainOrDestroy := []string{"", RemovalPolicyDestroy, RemovalPolicyRetain}
+	if !slices.Contains(retainOrDestroy, r.PromptBucket) {
+		return fmt.Errorf("removalPolicies.promptBucket must be %q or %q, got %q", RemovalPolicyDestroy, RemovalPolicyRetain, r.PromptBucket)
+	}
+	if !slices.Contains(retainOrDestroy, r.LogGroups) {
+		return fmt.Errorf("removalPolicies.logGroups must be %q or %q, got %q", RemovalPolicyDestroy, RemovalPolicyRetain, r.LogGroups)
+	}
+	if !slices.Contains(retainOrDestroy, r.KinesisStorageConfig) {
+		return fmt.Errorf("removalPolicies.kinesisStorageConfig must be %q or %q, got %q", RemovalPolicyDestroy, RemovalPolicyRetain, r.KinesisStorageConfig)
+	}
+	// A retained replication group would keep the subnet group, security group and VPC from being deleted, so only
+	// a final snapshot is supported
+	if !slices.Contains([]string{"", RemovalPolicyDestroy, RemovalPolicySnapshot}, r.Valkey) {
+		return fmt.Errorf("removalPolicies.valkey must be %q or %q, got %q", RemovalPolicyDestroy, RemovalPolicySnapshot, r.Valkey)
+	}
+	return nil
+}
+
+func validateTags(tags map[string]string) error {
+	for key, value := range tags {
+		if key == "" || len(key) > 128 {
+			return fmt.Errorf("tags: key %q must be between 1 and 128 characters", key)
+		}
+		if strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return fmt.Errorf("tags: key %q uses the reserved aws: prefix", key)
+		}
+		if len(value) > 256 {
+			return fmt.Errorf("tags: value for key %q must be at most 256 characters", key)
+		}
+	}
+	return nil
+}
+
+// externalIdPattern is the format IAM accepts for sts:ExternalId
+var externalIdPattern = regexp.MustCompile(`^[\w+=,.@:/-]+$`)
+
+func (a *AsappConfig) validate() error {
+	// The external ID protects the role against the confused deputy problem, it only does so when it cannot be
+	// guessed, so it is never derived from the deployment
+	if a.ExternalId == "" {
+		return fmt.Errorf("asapp.externalId is required, set it to the external ID agreed with ASAPP or to a random value shared with them")
+	}
+	if len(a.ExternalId) < 2 || len(a.ExternalId) > 1224 || !externalIdPattern.MatchString(a.ExternalId) {
+		return fmt.Errorf("asapp.externalId must be 2 to 1224 letters, digits or characters among +=,.@:/-")
+	}
+	for _, sourceIp := range a.AllowedSourceIps {
+		if _, _, err := net.ParseCIDR(sourceIp); err != nil && net.ParseIP(sourceIp) == nil {
+			return fmt.Errorf("asapp.allowedSourceIps: %q is not a valid IP address or CIDR block", sourceIp)
+		}
+	}
+	// IAM only accepts a maximum session duration between 1 and 12 hours
+	if a.MaxSessionDurationSeconds != 0 && (a.MaxSessionDurationSeconds < 3600 || a.MaxSessionDurationSeconds > 43200) {
+		return fmt.Errorf("asapp.maxSessionDurationSeconds must be between 3600 and 43200, got %d", a.MaxSessionDurationSeconds)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package config
+
+// Text-to-speech engines of Amazon Polly that can be selected in Amazon Connect flows
+const (
+	TextToSpeechEngineStandard   = "standard"
+	TextToSpeechEngineNeural     = "neural"
+	TextToSpeechEngineGenerative = "generative"
+)
+
+type pollyVoice struct {
+	languages []string
+	engines   []string
+}
+
+// pollyVoices lists the Amazon Polly voices, by voice ID, with the languages they speak and the engines they support.
+// See https://docs.aws.amazon.com/polly/latest/dg/available-voices.html, long-form only voices are left out as they
+// cannot be used in Amazon Connect.
+var pollyVoices = map[string]pollyVoice{
+	// English (US)
+	"Danielle": {[]string{"en-US"}, []string{"neural", "generative"}},
+	"Gregory":  {[]string{"en-US"}, []string{"neural"}},
+	"Ivy":      {[]string{"en-US"}, []string{"standard", "neural"}},
+	"Joanna":   {[]string{"en-US"}, []string{"standard", "neural", "generative"}},
+	"Kendra":   {[]string{"en-US"}, []string{"standard", "neural"}},
+	"Kimberly": {[]string{"en-US"}, []string{"standard", "neural"}},
+	"Salli":    {[]string{"en-US"}, []string{"standard", "neural", "generative"}},
+	"Joey":     {[]string{"en-US"}, []string{"standard", "neural"}},
+	"Justin":   {[]string{"en-US"}, []string{"standard", "neural"}},
+	"Kevin":    {[]string{"en-US"}, []string{"neural"}},
+	"Matthew":  {[]string{"en-US"}, []string{"standard", "neural", "generative"}},
+	"Ruth":     {[]string{"en-US"}, []string{"neural", "generative"}},
+	"Stephen":  {[]string{"en-US"}, []string{"neural", "generative"}},
+	// English (British)
+	"Amy":    {[]string{"en-GB"}, []string{"standard", "neural", "generative"}},
+	"Emma":   {[]string{"en-GB"}, []string{"standard", "neural"}},
+	"Brian":  {[]string{"en-GB"}, []string{"standard", "neural"}},
+	"Arthur": {[]string{"en-GB"}, []string{"neural"}},
+	// English (Australian)
+	"Nicole":  {[]string{"en-AU"}, []string{"standard"}},
+	"Olivia":  {[]string{"en-AU"}, []string{"neural", "generative"}},
+	"Russell": {[]string{"en-AU"}, []string{"standard"}},
+	// English (Indian)
+	"Aditi":   {[]string{"en-IN", "hi-IN"}, []string{"standard"}},
+	"Raveena": {[]string{"en-IN"}, []string{"standard"}},
+	"Kajal":   {[]string{"en-IN", "hi-IN"}, []string{"neural", "generative"}},
+	// Spanish (US)
+	"Lupe":     {[]string{"es-US"}, []string{"standard", "neural", "generative"}},
+	"Penelope": {[]string{"es-US"}, []string{"standard"}},
+	"Miguel":   {[]string{"es-US"}, []string{"standard"}},
+	"Pedro":    {[]string{"es-US"}, []string{"neural", "generative"}},
+	// Spanish (European)
+	"Conchita": {[]string{"es-ES"}, []string{"standard"}},
+	"Lucia":    {[]string{"es-ES"}, []string{"standard", "neural", "generative"}},
+	"Enrique":  {[]string{"es-ES"}, []string{"standard"}},
+	"Sergio":   {[]string{"es-ES"}, []string{"neural", "generative"}},
+	// Spanish (Mexican)
+	"Mia":    {[]string{"es-MX"}, []string{"standard", "neural", "generative"}},
+	"Andres": {[]string{"es-MX"}, []string{"neural", "generative"}},
+	// French
+	"Celine":  {[]string{"fr-FR"}, []string{"standard"}},
+	"Lea":     {[]string{"fr-FR"}, []string{"standard", "neural", "generative"}},
+	"Mathieu": {[]string{"fr-FR"}, []string{"standard"}},
+	"Remi":    {[]string{"fr-FR"}, []string{"neural", "generative"}},
+	// French (Canadian)
+	"Chantal":   {[]string{"fr-CA"}, []string{"standard"}},
+	"Gabrielle": {[]string{"fr-CA"}, []string{"neural", "generative"}},
+	"Liam":      {[]string{"fr-CA"}, []string{"neural", "generative"}},
+	// German
+	"Marlene": {[]string{"de-DE"}, []string{"standard"}},
+	"Vicki":   {[]string{"de-DE"}, []string{"standard", "neural", "generative"}},
+	"Hans":    {[]string{"de-DE"}, []string{"standard"}},
+	"Daniel":  {[]string{"de-DE"}, []string{"neural", "generative"}},
+	// Italian
+	"Carla":   {[]string{"it-IT"}, []string{"standard"}},
+	"Bianca":  {[]string{"it-IT"}, []string{"standard", "neural", "generative"}},
+	"Giorgio": {[]string{"it-IT"}, []string{"standard"}},
+	"Adriano": {[]string{"it-IT"}, []string{"neural", "generative"}},
+	// Portuguese
+	"Camila":    {[]string{"pt-BR"}, []string{"standard", "neural", "generative"}},
+	"Vitoria":   {[]string{"pt-BR"}, []string{"standard", "neural"}},
+	"Ricardo":   {[]string{"pt-BR"}, []string{"standard"}},
+	"Thiago":    {[]string{"pt-BR"}, []string{"neural", "generative"}},
+	"Ines":      {[]string{"pt-PT"}, []string{"standard", "neural", "generative"}},
+	"Cristiano": {[]string{"pt-PT"}, []string{"standard"}},
+	// Japanese
+	"Mizuki": {[]string{"ja-JP"}, []string{"standard"}},
+	"Takumi": {[]string{"ja-JP"}, []string{"standard", "neural"}},
+	"Kazuha": {[]string{"ja-JP"}, []string{"neural"}},
+	"Tomoko": {[]string{"ja-JP"}, []string{"neural"}},
+}
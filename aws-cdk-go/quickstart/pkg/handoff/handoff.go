@@ -0,0 +1,140 @@
+package handoff
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// Names of the stack outputs, they are the keys under the stack name in a `cdk deploy --outputs-file` result
+const (
+	OutputAccessRoleArn            = "iamrolearn"
+	OutputExternalId               = "externalid"
+	OutputPushActionLambdaArn      = "pushactionlambdaarn"
+	OutputEngageLambdaArn          = "engagelambdaarn"
+	OutputPullActionLambdaArn      = "pullactionlambdaarn"
+	OutputFlowModuleArn            = "flowmodulearn"
+	OutputFlowModuleId             = "flowmoduleid"
+	OutputConnectInstanceArn       = "connectinstancearn"
+	OutputValkeyEndpoint           = "valkeyendpoint"
+	OutputKinesisVideoStreamPrefix = "kinesisvideostreamprefix"
+	OutputBeepBopPromptId          = "beepbopshortpromptid"
+	OutputSilence1secondPromptId   = "silence1secondpromptid"
+	OutputSilence400msPromptId     = "silence400mspromptid"
+	OutputSampleContactFlowArn     = "samplecontactflowarn"
+	OutputClaimedPhoneNumber       = "claimedphonenumber"
+)
+
+// requiredOutputs are the values ASAPP needs to grant access to the deployment
+var requiredOutputs = []string{OutputAccessRoleArn, OutputExternalId, OutputPushActionLambdaArn}
+
+//go:embed handoff.md.tmpl
+var markdownTemplate string
+
+// Document is the information handed to ASAPP after a deployment.
+type Document struct {
+	StackName string `json:"stackName"`
+
+	// Values ASAPP needs to connect to the deployment
+	AccessRoleArn            string `json:"accessRoleArn"`
+	ExternalId               string `json:"externalId"`
+	PushActionLambdaArn      string `json:"pushActionLambdaArn"`
+	KinesisVideoStreamPrefix string `json:"kinesisVideoStreamPrefix"`
+
+	// Reference information about the deployed resources
+	ConnectInstanceArn   string            `json:"connectInstanceArn"`
+	FlowModuleArn        string            `json:"flowModuleArn"`
+	FlowModuleId         string            `json:"flowModuleId"`
+	FlowModuleArns       map[string]string `json:"flowModuleArns,omitempty"`
+	EngageLambdaArn      string            `json:"engageLambdaArn"`
+	PullActionLambdaArn  string            `json:"pullActionLambdaArn"`
+	ValkeyEndpoint       string            `json:"valkeyEndpoint"`
+	PromptIds            map[string]string `json:"promptIds"`
+	SampleContactFlowArn string            `json:"sampleContactFlowArn,omitempty"`
+	ClaimedPhoneNumber   string            `json:"claimedPhoneNumber,omitempty"`
+}
+
+// Load reads a `cdk deploy --outputs-file` result and builds the handoff document for stackName. stackName can be
+// empty when the file contains a single stack.
+func Load(r io.Reader, stackName string) (*Document, error) {
+	var stacks map[string]map[string]string
+	if err := json.NewDecoder(r).Decode(&stacks); err != nil {
+		return nil, fmt.Errorf("failed to decode outputs file: %w", err)
+	}
+
+	if stackName == "" {
+		if len(stacks) != 1 {
+			return nil, fmt.Errorf("outputs file contains %d stacks (%s), specify which one to use", len(stacks), strings.Join(slices.Sorted(maps.Keys(stacks)), ", "))
+		}
+		for name := range stacks {
+			stackName = name
+		}
+	}
+	outputs, ok := stacks[stackName]
+	if !ok {
+		return nil, fmt.Errorf("stack %s not found in outputs file", stackName)
+	}
+
+	for _, key := range requiredOutputs {
+		if outputs[key] == "" {
+			return nil, fmt.Errorf("stack %s is missing the %s output, redeploy it with the current quickstart version", stackName, key)
+		}
+	}
+
+	doc := &Document{
+		StackName:                stackName,
+		AccessRoleArn:            outputs[OutputAccessRoleArn],
+		ExternalId:               outputs[OutputExternalId],
+		PushActionLambdaArn:      outputs[OutputPushActionLambdaArn],
+		KinesisVideoStreamPrefix: outputs[OutputKinesisVideoStreamPrefix],
+		ConnectInstanceArn:       outputs[OutputConnectInstanceArn],
+		FlowModuleArn:            outputs[OutputFlowModuleArn],
+		FlowModuleId:             outputs[OutputFlowModuleId],
+		EngageLambdaArn:          outputs[OutputEngageLambdaArn],
+		PullActionLambdaArn:      outputs[OutputPullActionLambdaArn],
+		ValkeyEndpoint:           outputs[OutputValkeyEndpoint],
+		SampleContactFlowArn:     outputs[OutputSampleContactFlowArn],
+		ClaimedPhoneNumber:       outputs[OutputClaimedPhoneNumber],
+		PromptIds:                map[string]string{},
+	}
+	// Named modules are output as flowmodulearn<name>, flowmodulearn holds the first module
+	for key, value := range outputs {
+		if name, ok := strings.CutPrefix(key, OutputFlowModuleArn); ok && name != "" && value != "" {
+			if doc.FlowModuleArns == nil {
+				doc.FlowModuleArns = map[string]string{}
+			}
+			doc.FlowModuleArns[name] = value
+		}
+	}
+	for name, key := range map[string]string{
+		"asappBeepBop":        OutputBeepBopPromptId,
+		"asappSilence1second": OutputSilence1secondPromptId,
+		"asappSilence400ms":   OutputSilence400msPromptId,
+	} {
+		if outputs[key] != "" {
+			doc.PromptIds[name] = outputs[key]
+		}
+	}
+	return doc, nil
+}
+
+// WriteJSON writes the document as indented JSON.
+func (d *Document) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+// WriteMarkdown writes the document as Markdown that can be pasted into an email or a ticket.
+func (d *Document) WriteMarkdown(w io.Writer) error {
+	tmpl, err := template.New("handoff").Parse(markdownTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, d)
+}
@@ -0,0 +1,114 @@
+package quickstart
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsappconfig"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// JSON schema AWS AppConfig validates new versions of the runtime configuration against
+//
+//go:embed appconfig.schema.json
+var runtimeConfigSchema string
+
+// runtimeConfig is the configuration the Lambda functions read from AWS AppConfig, it holds the same values as
+// the generated ssmlConversions.mjs, attributesToInputVariables.mjs, inputVariables.mjs and modules.mjs modules
+type runtimeConfig struct {
+	SSMLConversions               []config.SSMLConversion `json:"ssmlConversions"`
+	AttributesToInputVariablesMap map[string]string       `json:"attributesToInputVariablesMap"`
+	InputVariables                []config.InputVariable  `json:"inputVariables"`
+	// Modules holds the configuration of the named Contact Flow Modules, keyed by module name
+	Modules map[string]runtimeConfig `json:"modules,omitempty"`
+}
+
+// newModuleRuntimeConfig returns the runtime configuration of a module, with empty values instead of nil ones
+func newModuleRuntimeConfig(ssmlConversions []config.SSMLConversion, attributesToInputVariables map[string]string, inputVariables []config.InputVariable) runtimeConfig {
+	if ssmlConversions == nil {
+		ssmlConversions = []config.SSMLConversion{}
+	}
+	if attributesToInputVariables == nil {
+		attributesToInputVariables = map[string]string{}
+	}
+	if inputVariables == nil {
+		inputVariables = []config.InputVariable{}
+	}
+	return runtimeConfig{
+		SSMLConversions:               ssmlConversions,
+		AttributesToInputVariablesMap: attributesToInputVariables,
+		InputVariables:                inputVariables,
+	}
+}
+
+// runtimeConfigResources is the AWS AppConfig configuration the Lambda functions read through the AppConfig
+// Lambda extension
+type runtimeConfigResources struct {
+	environment awsappconfig.Environment
+	profileName *string
+	layer       awslambda.ILayerVersion
+	path        *string
+}
+
+// newRuntimeConfig creates an AWS AppConfig application, environment and hosted configuration profile seeded with
+// the SSML conversions, attribute maps and module configurations of the deployment. Later versions of the profile
+// are validated against runtimeConfigSchema.
+func newRuntimeConfig(stack awscdk.Stack, cfg *config.Config, ssmlConversions []config.SSMLConversion, modules map[string]runtimeConfig) runtimeConfigResources {
+	seed := newModuleRuntimeConfig(ssmlConversions, cfg.AttributesToInputVariablesMap, cfg.InputVariables)
+	seed.Modules = modules
+	content, err := jsonValue(seed)
+	if err != nil {
+		log.Fatalf("Failed to marshal AppConfig configuration: %v", err)
+	}
+
+	deploymentStrategyId := cfg.AppConfig.DeploymentStrategyId
+	if deploymentStrategyId == "" {
+		deploymentStrategyId = "AppConfig.AllAtOnce"
+	}
+
+	application := awsappconfig.NewApplication(stack, generateObjectName(cfg, "appconfig-application"), &awsappconfig.ApplicationProps{
+		ApplicationName: generateObjectName(cfg, "generativeagent"),
+		Description:     jsii.String("Runtime configuration of the ASAPP GenerativeAgent Lambda functions"),
+	})
+	environment := awsappconfig.NewEnvironment(stack, generateObjectName(cfg, "appconfig-environment"), &awsappconfig.EnvironmentProps{
+		Application:     application,
+		EnvironmentName: jsii.String("lambdas"),
+	})
+	profileName := jsii.String("runtime-config")
+	awsappconfig.NewHostedConfiguration(stack, generateObjectName(cfg, "appconfig-runtime-config"), &awsappconfig.HostedConfigurationProps{
+		Application: application,
+		Name:        profileName,
+		Description: jsii.String("SSML conversions and input variable mappings, seeded from the CDK config"),
+		Content:     awsappconfig.ConfigurationContent_FromInlineJson(jsii.String(content), nil),
+		Validators: &[]awsappconfig.IValidator{
+			awsappconfig.JsonSchemaValidator_FromInline(jsii.String(runtimeConfigSchema)),
+		},
+		DeployTo: &[]awsappconfig.IEnvironment{environment},
+		DeploymentStrategy: awsappconfig.DeploymentStrategy_FromDeploymentStrategyId(stack, generateObjectName(cfg, "appconfig-deployment-strategy"),
+			awsappconfig.DeploymentStrategyId_FromString(jsii.String(deploymentStrategyId))),
+	})
+
+	return runtimeConfigResources{
+		environment: environment,
+		profileName: profileName,
+		layer: awslambda.LayerVersion_FromLayerVersionArn(stack, generateObjectName(cfg, "appconfig-extension-layer"),
+			awsappconfig.Application_GetLambdaLayerVersionArn(jsii.String(cfg.Region), awsappconfig.Platform_X86_64)),
+		path: jsii.String(fmt.Sprintf("/applications/%s/environments/%s/configurations/%s",
+			*application.Name(), *environment.Name(), *profileName)),
+	}
+}
+
+// readRuntimeConfig lets function read the runtime configuration through the AppConfig Lambda extension
+func (r runtimeConfigResources) readRuntimeConfig(cfg *config.Config, function awslambda.Function) {
+	function.AddLayers(r.layer)
+	function.AddEnvironment(jsii.String("ASAPP_APPCONFIG_PATH"), r.path, nil)
+	if cfg.AppConfig.PollIntervalSeconds != 0 {
+		function.AddEnvironment(jsii.String("AWS_APPCONFIG_EXTENSION_POLL_INTERVAL_SECONDS"), jsii.String(fmt.Sprint(cfg.AppConfig.PollIntervalSeconds)), nil)
+	}
+	r.environment.GrantReadConfig(function)
+}
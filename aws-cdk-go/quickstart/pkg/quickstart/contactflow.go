@@ -0,0 +1,122 @@
+package quickstart
+
+import (
+	"encoding/json"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+)
+
+// Contact attributes shared between the Contact Flow Module and the flows invoking it
+const (
+	companyMarkerAttribute = "ASAPP_CompanyMarker"
+	dispositionAttribute   = "ASAPP_Disposition"
+
+	// Values of dispositionAttribute set by the SetDisposition* blocks of the module
+	dispositionTransferToAgent  = "transferToAgent"
+	dispositionTransferToSystem = "transferToSystem"
+	dispositionDisengage        = "disengage"
+)
+
+// Identifiers of the sample contact flow blocks
+const (
+	sampleFlowSetCompanyMarker   = "SetCompanyMarker"
+	sampleFlowInvokeModule       = "InvokeGenerativeAgent"
+	sampleFlowRouteDisposition   = "RouteOnDisposition"
+	sampleFlowSetAgentQueue      = "SetAgentQueue"
+	sampleFlowTransferToQueue    = "TransferToAgentQueue"
+	sampleFlowTransferToFallback = "TransferToFallbackFlow"
+	sampleFlowDisconnect         = "Disconnect"
+)
+
+// buildSampleContactFlow builds an inbound contact flow that invokes the Contact Flow Module and routes the contact
+// on the disposition set by the module: transferToAgent goes to the configured queue, transferToSystem to the
+// fallback flow, and disengage disconnects. Errors go to the fallback flow, or the queue when there is no fallback
+// flow. Branches without a configured target disconnect the contact. When moduleSetsQueue is set, the module has
+// already set the queue and transferToAgent goes straight to the queue transfer.
+func buildSampleContactFlow(flowModuleId string, flowCfg config.SampleContactFlowConfig, moduleSetsQueue bool) ([]byte, error) {
+	agentAction := sampleFlowDisconnect
+	if flowCfg.QueueArn != "" {
+		agentAction = sampleFlowSetAgentQueue
+	} else if moduleSetsQueue {
+		agentAction = sampleFlowTransferToQueue
+	}
+	systemAction := sampleFlowDisconnect
+	if flowCfg.FallbackFlowArn != "" {
+		systemAction = sampleFlowTransferToFallback
+	}
+	errorAction := systemAction
+	if errorAction == sampleFlowDisconnect {
+		errorAction = agentAction
+	}
+
+	actions := []any{
+		newFlowAction(sampleFlowSetCompanyMarker, "UpdateContactAttributes",
+			newOrderedMap(
+				"Attributes", newOrderedMap(companyMarkerAttribute, flowCfg.CompanyMarker),
+				"TargetContact", "Current",
+			),
+			newFlowTransitions(sampleFlowInvokeModule, flowError{"NoMatchingError", sampleFlowDisconnect}),
+		),
+		newFlowAction(sampleFlowInvokeModule, "InvokeFlowModule",
+			newOrderedMap("FlowModuleId", flowModuleId),
+			newFlowTransitions(sampleFlowRouteDisposition, flowError{"NoMatchingError", errorAction}),
+		),
+		newFlowAction(sampleFlowRouteDisposition, "Compare",
+			newOrderedMap("ComparisonValue", "$.Attributes."+dispositionAttribute),
+			newCompareTransitions(errorAction, []flowCondition{
+				{dispositionTransferToAgent, agentAction},
+				{dispositionTransferToSystem, systemAction},
+				{dispositionDisengage, sampleFlowDisconnect},
+			}),
+		),
+	}
+	metadata := newOrderedMap(
+		sampleFlowSetCompanyMarker, newActionMetadata(200, 40),
+		sampleFlowInvokeModule, newActionMetadata(440, 40),
+		sampleFlowRouteDisposition, newActionMetadata(680, 40),
+	)
+
+	if flowCfg.QueueArn != "" {
+		actions = append(actions,
+			newFlowAction(sampleFlowSetAgentQueue, "UpdateContactTargetQueue",
+				newOrderedMap("QueueId", flowCfg.QueueArn),
+				newFlowTransitions(sampleFlowTransferToQueue, flowError{"NoMatchingError", sampleFlowDisconnect}),
+			),
+		)
+		metadata.Set(sampleFlowSetAgentQueue, newActionMetadata(960, -120))
+	}
+	if flowCfg.QueueArn != "" || moduleSetsQueue {
+		actions = append(actions,
+			newFlowAction(sampleFlowTransferToQueue, "TransferContactToQueue",
+				newOrderedMap(),
+				newFlowTransitions("",
+					flowError{"QueueAtCapacity", sampleFlowDisconnect},
+					flowError{"NoMatchingError", sampleFlowDisconnect},
+				),
+			),
+		)
+		metadata.Set(sampleFlowTransferToQueue, newActionMetadata(1200, -120))
+	}
+	if flowCfg.FallbackFlowArn != "" {
+		actions = append(actions,
+			newFlowAction(sampleFlowTransferToFallback, "TransferToFlow",
+				newOrderedMap("ContactFlowId", flowCfg.FallbackFlowArn),
+				newFlowTransitions("", flowError{"NoMatchingError", sampleFlowDisconnect}),
+			),
+		)
+		metadata.Set(sampleFlowTransferToFallback, newActionMetadata(960, 120))
+	}
+	actions = append(actions, newFlowAction(sampleFlowDisconnect, "DisconnectParticipant", newOrderedMap(), newOrderedMap()))
+	metadata.Set(sampleFlowDisconnect, newActionMetadata(1440, 40))
+
+	flow := newOrderedMap(
+		"Version", "2019-10-30",
+		"StartAction", sampleFlowSetCompanyMarker,
+		"Metadata", newOrderedMap(
+			"entryPointPosition", newOrderedMap("x", 40, "y", 40),
+			"ActionMetadata", metadata,
+		),
+		"Actions", actions,
+	)
+	return json.MarshalIndent(flow, "", "  ")
+}
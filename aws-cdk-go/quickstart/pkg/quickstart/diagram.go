@@ -0,0 +1,222 @@
+package quickstart
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// diagramNode is an action of the module drawn in a diagram
+type diagramNode struct {
+	id    string
+	lines []string
+	// missing is set for the targets of transitions that are not actions of the module
+	missing bool
+}
+
+// diagramEdge is a transition drawn in a diagram
+type diagramEdge struct {
+	from, to string
+	label    string
+	isError  bool
+}
+
+// moduleDiagram is the action graph of a module laid out for rendering, nodes are in the order of the module
+type moduleDiagram struct {
+	start string
+	nodes []diagramNode
+	edges []diagramEdge
+}
+
+func newModuleDiagram(data *orderedmap.OrderedMap) (*moduleDiagram, error) {
+	g, err := buildFlowGraph(data)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &moduleDiagram{}
+	ids := map[string]string{}
+	nodeID := func(identifier string) string {
+		id, ok := ids[identifier]
+		if !ok {
+			id = fmt.Sprintf("a%d", len(ids))
+			ids[identifier] = id
+		}
+		return id
+	}
+	for _, action := range g.actions {
+		d.nodes = append(d.nodes, diagramNode{id: nodeID(action.identifier), lines: g.actionLabel(action)})
+	}
+	for _, action := range g.actions {
+		for _, edge := range action.edges {
+			if _, ok := g.action(edge.target); !ok {
+				if _, drawn := ids[edge.target]; !drawn {
+					d.nodes = append(d.nodes, diagramNode{id: nodeID(edge.target), lines: []string{edge.target, "(not an action)"}, missing: true})
+				}
+			}
+			label := edge.branch
+			if edge.operator != "" && edge.operator != "Equals" {
+				label = edge.operator + " " + label
+			}
+			d.edges = append(d.edges, diagramEdge{from: nodeID(action.identifier), to: nodeID(edge.target), label: label, isError: edge.isError})
+		}
+	}
+	if g.startAction != "" {
+		d.start = nodeID(g.startAction)
+	}
+	return d, nil
+}
+
+// actionLabel returns the lines of the label of an action: its type, the friendly name given in the console or the
+// Identifier, and what the action works on when the metadata or parameters tell it
+func (g *flowGraph) actionLabel(action flowAction) []string {
+	metadata, _, _ := objectField(g.actionMetadata, action.identifier)
+	lines := []string{action.actionType}
+	if friendly, _ := metadata.Get("isFriendlyName"); friendly == true {
+		lines = append(lines, action.identifier)
+	}
+
+	if promptName, ok, _ := stringField(metadata, "promptName"); ok {
+		lines = append(lines, "prompt "+promptName)
+	} else if parameters, ok, _ := objectField(metadata, "parameters"); ok {
+		lambda, _, _ := objectField(parameters, "LambdaFunctionARN")
+		if displayName, ok, _ := stringField(lambda, "displayName"); ok {
+			lines = append(lines, "function "+displayName)
+		}
+	}
+	for _, parameter := range []string{"ComparisonValue", "Text", "SSML", "MediaStreamingState"} {
+		if value, ok, _ := stringField(action.parameters, parameter); ok {
+			lines = append(lines, parameter+" "+value)
+		}
+	}
+	lines = append(lines, attributeAssignments(action)...)
+	if len(lines) == 1 {
+		lines = append(lines, action.identifier)
+	}
+	return lines
+}
+
+// attributeAssignments describes the contact and flow attributes an action sets, e.g. ASAPP_Disposition = error
+func attributeAssignments(action flowAction) []string {
+	assignments := []string{}
+	attributes, _, _ := objectField(action.parameters, "Attributes")
+	for _, name := range attributes.Keys() {
+		value, _ := attributes.Get(name)
+		assignments = append(assignments, fmt.Sprintf("%s = %v", name, value))
+	}
+	flowAttributes, _, _ := objectField(action.parameters, "FlowAttributes")
+	for _, name := range flowAttributes.Keys() {
+		attribute, _, _ := objectField(flowAttributes, name)
+		value, _ := attribute.Get("Value")
+		assignments = append(assignments, fmt.Sprintf("%s = %v", name, value))
+	}
+	return assignments
+}
+
+// WriteModuleDOT writes the action graph of a Contact Flow Module as a Graphviz DOT digraph. Error branches are
+// drawn as red dashed edges.
+func WriteModuleDOT(w io.Writer, data *orderedmap.OrderedMap) error {
+	d, err := newModuleDiagram(data)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph FlowModule {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded, fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+	if d.start != "" {
+		b.WriteString("  start [label=\"Start\", shape=circle];\n")
+	}
+	for _, node := range d.nodes {
+		attributes := fmt.Sprintf("label=%s", dotString(strings.Join(node.lines, "\n")))
+		if node.missing {
+			attributes += ", style=dashed, color=red"
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", node.id, attributes)
+	}
+	if d.start != "" {
+		fmt.Fprintf(&b, "  start -> %s;\n", d.start)
+	}
+	for _, edge := range d.edges {
+		attributes := []string{}
+		if edge.label != "" {
+			attributes = append(attributes, "label="+dotString(edge.label))
+		}
+		if edge.isError {
+			attributes = append(attributes, "color=red", "fontcolor=red", "style=dashed")
+		}
+		if len(attributes) == 0 {
+			fmt.Fprintf(&b, "  %s -> %s;\n", edge.from, edge.to)
+			continue
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", edge.from, edge.to, strings.Join(attributes, ", "))
+	}
+	b.WriteString("}\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// WriteModuleMermaid writes the action graph of a Contact Flow Module as a Mermaid flowchart, which GitHub renders
+// in Markdown. Error branches are drawn as red dotted edges.
+func WriteModuleMermaid(w io.Writer, data *orderedmap.OrderedMap) error {
+	d, err := newModuleDiagram(data)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	if d.start != "" {
+		b.WriteString("  start((Start))\n")
+	}
+	for _, node := range d.nodes {
+		fmt.Fprintf(&b, "  %s[%s]\n", node.id, mermaidString(strings.Join(node.lines, "\n")))
+		if node.missing {
+			fmt.Fprintf(&b, "  style %s stroke:red,stroke-dasharray:5 5\n", node.id)
+		}
+	}
+
+	// Mermaid styles edges by their position, counted from 0 in the order they are declared
+	link := 0
+	errorLinks := []string{}
+	if d.start != "" {
+		fmt.Fprintf(&b, "  start --> %s\n", d.start)
+		link++
+	}
+	for _, edge := range d.edges {
+		arrow := "-->"
+		if edge.isError {
+			arrow = "-.->"
+			errorLinks = append(errorLinks, fmt.Sprint(link))
+		}
+		if edge.label == "" {
+			fmt.Fprintf(&b, "  %s %s %s\n", edge.from, arrow, edge.to)
+		} else {
+			fmt.Fprintf(&b, "  %s %s|%s| %s\n", edge.from, arrow, mermaidString(edge.label), edge.to)
+		}
+		link++
+	}
+	if len(errorLinks) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:red,color:red\n", strings.Join(errorLinks, ","))
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// dotString quotes s as a DOT string, newlines start a new line of the label
+func dotString(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+// mermaidString quotes s as a Mermaid string, quotes are written as entity codes and newlines as line breaks
+func mermaidString(s string) string {
+	s = strings.NewReplacer(`"`, "#quot;", "\n", "<br/>").Replace(s)
+	return `"` + s + `"`
+}
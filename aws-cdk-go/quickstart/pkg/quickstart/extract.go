@@ -0,0 +1,419 @@
+package quickstart
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/iancoleman/orderedmap"
+)
+
+// ExtractFlowModule turns a Contact Flow Module exported from the Amazon Connect console back into a flow module
+// template: the changes of the built-in transforms are reverted, and the values of the placeholders of the template,
+// such as the Lambda functions, prompts and their display names, are set back to the placeholders. It returns the
+// template, formatted like the one in the repository, the changes made, and warnings about the ARNs of instance
+// resources left in the module, which are not changed when the module is deployed.
+func ExtractFlowModule(exported, template []byte) ([]byte, []string, []string, error) {
+	exportedModule, err := unmarshalFlowModule(exported)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("exported module: %w", err)
+	}
+	templateModule, err := unmarshalFlowModule(template)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("template: %w", err)
+	}
+
+	changes, err := revertBuiltinTransforms(&exportedModule, templateModule)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("exported module: %w", err)
+	}
+	restorePlaceholders(exportedModule, templateModule, "", &changes)
+	warnings := []string{}
+	instanceResourceWarnings(exportedModule, "", &warnings)
+
+	content, err := marshalFlowModule(exportedModule)
+	return content, changes, warnings, err
+}
+
+// injectedActionPattern matches the Identifier of the actions added by the built-in transforms
+var injectedActionPattern = regexp.MustCompile(`^(SetTextToSpeechVoice|SetLanguage|RouteTransferToAgentQueue|SetTransferToAgentQueue[0-9]+)$`)
+
+// revertBuiltinTransforms undoes the changes the built-in transforms made to a deployed module, so they are not
+// added twice when the template is deployed again: it removes the actions added by text-to-speech-voice and
+// transfer-to-agent-queue, speaks SpeakResponse as Text again, and removes the attributes of output-variables and the
+// moduleName parameter of module-name that the template does not have.
+func revertBuiltinTransforms(module *orderedmap.OrderedMap, template orderedmap.OrderedMap) ([]string, error) {
+	changes := []string{}
+	if err := removeInjectedActions(module, template, &changes); err != nil {
+		return nil, err
+	}
+	if err := revertSpeakResponseToText(module, template, &changes); err != nil {
+		return nil, err
+	}
+	if err := revertOutputVariables(module, template, &changes); err != nil {
+		return nil, err
+	}
+	if err := revertModuleName(module, template, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// removeInjectedActions removes the actions added by the built-in transforms that the template does not have, and
+// points the transitions to them to the template action they lead to
+func removeInjectedActions(module *orderedmap.OrderedMap, template orderedmap.OrderedMap, changes *[]string) error {
+	actions, _, err := objectListField(*module, "Actions")
+	if err != nil {
+		return err
+	}
+	nextActions := map[string]string{}
+	for i, action := range actions {
+		identifier, _, err := stringField(action, "Identifier")
+		if err != nil {
+			return fmt.Errorf("Actions[%d]: %w", i, err)
+		}
+		if !injectedActionPattern.MatchString(identifier) {
+			continue
+		}
+		if _, ok, err := findAction(&template, identifier); err != nil {
+			return fmt.Errorf("template: %w", err)
+		} else if ok {
+			continue
+		}
+		// Added actions always continue towards the action they were inserted before, errors included
+		transitions, ok, err := objectField(action, "Transitions")
+		if err != nil || !ok {
+			return actionError(identifier, err)
+		}
+		nextAction, _, err := stringField(transitions, "NextAction")
+		if err != nil {
+			return actionError(identifier, err)
+		}
+		nextActions[identifier] = nextAction
+	}
+	if len(nextActions) == 0 {
+		return nil
+	}
+
+	// Follows the chain of added actions, e.g. SetTextToSpeechVoice, SetLanguage, to the template action
+	templateAction := func(identifier string) string {
+		for range len(nextActions) + 1 {
+			next, ok := nextActions[identifier]
+			if !ok {
+				break
+			}
+			identifier = next
+		}
+		return identifier
+	}
+	for _, identifier := range slices.Sorted(maps.Keys(nextActions)) {
+		if err := redirectAllTransitions(module, identifier, templateAction(identifier)); err != nil {
+			return err
+		}
+	}
+
+	kept := []any{}
+	for _, action := range actions {
+		identifier, _, _ := stringField(action, "Identifier")
+		if _, ok := nextActions[identifier]; ok {
+			*changes = append(*changes, fmt.Sprintf("removed %s added by a built-in transform, its transitions go to %s", identifier, templateAction(identifier)))
+			continue
+		}
+		kept = append(kept, action)
+	}
+	module.Set("Actions", kept)
+
+	moduleMetadata, ok, err := objectField(*module, "Metadata")
+	if err != nil || !ok {
+		return err
+	}
+	actionMetadata, ok, err := objectField(moduleMetadata, "ActionMetadata")
+	if err != nil || !ok {
+		return metadataError(err)
+	}
+	for identifier := range nextActions {
+		actionMetadata.Delete(identifier)
+	}
+	moduleMetadata.Set("ActionMetadata", actionMetadata)
+	module.Set("Metadata", moduleMetadata)
+	return nil
+}
+
+// revertSpeakResponseToText undoes speak-ssml when SpeakResponse speaks Text in the template
+func revertSpeakResponseToText(module *orderedmap.OrderedMap, template orderedmap.OrderedMap, changes *[]string) error {
+	templateParameters, ok, err := actionParameters(&template, "SpeakResponse")
+	if err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	if _, ok := templateParameters.Get("Text"); !ok {
+		return nil
+	}
+	if _, ok := templateParameters.Get("SSML"); ok {
+		return nil
+	}
+
+	action, ok, err := findAction(module, "SpeakResponse")
+	if err != nil || !ok {
+		return err
+	}
+	parameters, ok, err := objectField(action, "Parameters")
+	if err != nil || !ok {
+		return actionError("SpeakResponse", err)
+	}
+	ssml, ok := parameters.Get("SSML")
+	if !ok {
+		return nil
+	}
+	if _, ok := parameters.Get("Text"); ok {
+		return nil
+	}
+	parameters.Set("Text", ssml)
+	parameters.Delete("SSML")
+	action.Set("Parameters", parameters)
+	*changes = append(*changes, "SpeakResponse speaks its text as Text again, speak-ssml sets SSML when deployed")
+	return nil
+}
+
+// revertOutputVariables removes the attributes of ExtractOutputVariables set by output-variables that the template
+// does not have
+func revertOutputVariables(module *orderedmap.OrderedMap, template orderedmap.OrderedMap, changes *[]string) error {
+	templateParameters, ok, err := actionParameters(&template, "ExtractOutputVariables")
+	if err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	templateAttributes, _, err := objectField(templateParameters, "Attributes")
+	if err != nil {
+		return fmt.Errorf("template: %w", actionError("ExtractOutputVariables", err))
+	}
+
+	action, ok, err := findAction(module, "ExtractOutputVariables")
+	if err != nil || !ok {
+		return err
+	}
+	parameters, ok, err := objectField(action, "Parameters")
+	if err != nil || !ok {
+		return actionError("ExtractOutputVariables", err)
+	}
+	attributes, ok, err := objectField(parameters, "Attributes")
+	if err != nil || !ok {
+		return actionError("ExtractOutputVariables", err)
+	}
+	for _, attribute := range attributes.Keys() {
+		if _, ok := templateAttributes.Get(attribute); ok {
+			continue
+		}
+		value, _, _ := stringField(attributes, attribute)
+		if !strings.HasPrefix(value, "$.External.outputVariables.") {
+			continue
+		}
+		attributes.Delete(attribute)
+		*changes = append(*changes, fmt.Sprintf("removed attribute %s set to %s, output-variables sets it from outputVariablesToAttributesMap when deployed", attribute, value))
+	}
+	parameters.Set("Attributes", attributes)
+	action.Set("Parameters", parameters)
+	return nil
+}
+
+// revertModuleName removes the moduleName parameter set by module-name from Engage and PullAction when the template
+// does not have it
+func revertModuleName(module *orderedmap.OrderedMap, template orderedmap.OrderedMap, changes *[]string) error {
+	for _, identifier := range []string{"Engage", "PullAction"} {
+		templateParameters, ok, err := actionParameters(&template, identifier)
+		if err != nil {
+			return fmt.Errorf("template: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		templateAttributes, hasTemplateAttributes, err := objectField(templateParameters, "LambdaInvocationAttributes")
+		if err != nil {
+			return fmt.Errorf("template: %w", actionError(identifier, err))
+		}
+		if _, ok := templateAttributes.Get("moduleName"); hasTemplateAttributes && ok {
+			continue
+		}
+
+		action, ok, err := findAction(module, identifier)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		parameters, ok, err := objectField(action, "Parameters")
+		if err != nil || !ok {
+			return actionError(identifier, err)
+		}
+		attributes, ok, err := objectField(parameters, "LambdaInvocationAttributes")
+		if err != nil || !ok {
+			if err != nil {
+				return actionError(identifier, err)
+			}
+			continue
+		}
+		moduleName, ok, _ := stringField(attributes, "moduleName")
+		if !ok {
+			continue
+		}
+		attributes.Delete("moduleName")
+		if len(attributes.Keys()) == 0 && !hasTemplateAttributes {
+			parameters.Delete("LambdaInvocationAttributes")
+		} else {
+			parameters.Set("LambdaInvocationAttributes", attributes)
+		}
+		action.Set("Parameters", parameters)
+		*changes = append(*changes, fmt.Sprintf("removed moduleName %s from %s, module-name sets it for named modules when deployed", moduleName, identifier))
+	}
+	return nil
+}
+
+// actionParameters returns the Parameters of the action with the given Identifier
+func actionParameters(data *orderedmap.OrderedMap, identifier string) (orderedmap.OrderedMap, bool, error) {
+	action, ok, err := findAction(data, identifier)
+	if err != nil || !ok {
+		return orderedmap.OrderedMap{}, false, err
+	}
+	parameters, ok, err := objectField(action, "Parameters")
+	if err != nil {
+		return orderedmap.OrderedMap{}, false, actionError(identifier, err)
+	}
+	return parameters, ok, nil
+}
+
+// restorePlaceholders sets the strings of value back to the string at the same place in templateValue when the
+// template string has placeholders the string is a resolution of. Actions are matched by Identifier.
+func restorePlaceholders(value, templateValue any, path string, changes *[]string) {
+	switch v := value.(type) {
+	case orderedmap.OrderedMap:
+		t, ok := templateValue.(orderedmap.OrderedMap)
+		if !ok {
+			return
+		}
+		for _, key := range v.Keys() {
+			nested, _ := v.Get(key)
+			templateNested, ok := t.Get(key)
+			if !ok {
+				continue
+			}
+			nestedPath := strings.TrimPrefix(path+"."+key, ".")
+			if s, ok := nested.(string); ok {
+				if restored, ok := restoredPlaceholders(s, templateNested); ok {
+					v.Set(key, restored)
+					*changes = append(*changes, fmt.Sprintf("set %s back to %s", nestedPath, restored))
+				}
+				continue
+			}
+			restorePlaceholders(nested, templateNested, nestedPath, changes)
+		}
+	case []any:
+		t, ok := templateValue.([]any)
+		if !ok {
+			return
+		}
+		for i, item := range v {
+			itemPath := listItemPath(path, i, item)
+			templateItem, ok := matchingTemplateItem(item, t, i)
+			if !ok {
+				continue
+			}
+			if s, ok := item.(string); ok {
+				if restored, ok := restoredPlaceholders(s, templateItem); ok {
+					v[i] = restored
+					*changes = append(*changes, fmt.Sprintf("set %s back to %s", itemPath, restored))
+				}
+				continue
+			}
+			restorePlaceholders(item, templateItem, itemPath, changes)
+		}
+	}
+}
+
+// matchingTemplateItem returns the item of the template list that matches the i-th item of a module list: the action
+// with the same Identifier for actions, the item at the same position otherwise
+func matchingTemplateItem(item any, templateList []any, i int) (any, bool) {
+	if object, ok := item.(orderedmap.OrderedMap); ok {
+		if identifier, ok, _ := stringField(object, "Identifier"); ok {
+			for _, templateItem := range templateList {
+				templateObject, _ := templateItem.(orderedmap.OrderedMap)
+				if templateIdentifier, _, _ := stringField(templateObject, "Identifier"); templateIdentifier == identifier {
+					return templateItem, true
+				}
+			}
+			return nil, false
+		}
+	}
+	if i < len(templateList) {
+		return templateList[i], true
+	}
+	return nil, false
+}
+
+// restoredPlaceholders returns the template string when it has placeholders and s is that string with the
+// placeholders resolved to any value
+func restoredPlaceholders(s string, templateValue any) (string, bool) {
+	t, ok := templateValue.(string)
+	if !ok || s == t || !placeholderPattern.MatchString(t) {
+		return "", false
+	}
+	literals := placeholderPattern.Split(t, -1)
+	for i, literal := range literals {
+		literals[i] = regexp.QuoteMeta(literal)
+	}
+	pattern := regexp.MustCompile("^" + strings.Join(literals, ".+") + "$")
+	return t, pattern.MatchString(s)
+}
+
+// instanceResourceWarnings warns about the ARNs of resources of an Amazon Connect instance, such as queues, found in
+// value. Without a placeholder UpdateResourcesARN only moves them to the region and account of the deployment, they
+// would still refer to the instance the module was exported from.
+func instanceResourceWarnings(value any, path string, warnings *[]string) {
+	switch v := value.(type) {
+	case orderedmap.OrderedMap:
+		for _, key := range v.Keys() {
+			nested, _ := v.Get(key)
+			instanceResourceWarnings(nested, strings.TrimPrefix(path+"."+key, "."), warnings)
+		}
+	case []any:
+		for i, item := range v {
+			instanceResourceWarnings(item, listItemPath(path, i, item), warnings)
+		}
+	case string:
+		if !arn.IsARN(v) {
+			return
+		}
+		parsed, err := arn.Parse(v)
+		if err == nil && strings.HasPrefix(parsed.Resource, "instance/") {
+			*warnings = append(*warnings, fmt.Sprintf("%s: %s is a resource of the instance the module was exported from", path, v))
+		}
+	}
+}
+
+// unmarshalFlowModule unmarshals a module without escaping HTML characters when it is marshalled again
+func unmarshalFlowModule(content []byte) (orderedmap.OrderedMap, error) {
+	module := newOrderedMap()
+	err := json.Unmarshal(content, &module)
+	return module, err
+}
+
+// marshalFlowModule formats a module like the flow module template of the repository
+func marshalFlowModule(module orderedmap.OrderedMap) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(module); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
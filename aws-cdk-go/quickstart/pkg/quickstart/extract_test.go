@@ -0,0 +1,63 @@
+package quickstart
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+)
+
+const flowModuleTemplatePath = "../../../../flow-modules/template/ASAPPGenerativeAgent.json"
+
+func TestExtractFlowModuleRevertsBuiltinTransforms(t *testing.T) {
+	template, err := os.ReadFile(flowModuleTemplatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources := ModuleResources{
+		ConnectInstanceArn:  "arn:aws:connect:us-east-1:111122223333:instance/instance-id",
+		EngageLambdaArn:     "arn:aws:lambda:us-east-1:111122223333:function:engage:prod",
+		PullActionLambdaArn: "arn:aws:lambda:us-east-1:111122223333:function:pullaction:prod",
+		PromptIds:           map[string]string{promptWait1s: "wait-1s", promptWait400ms: "wait-400ms", promptBeepBop: "beep-bop"},
+	}
+	// Every built-in transform changes the module
+	module := config.ModuleConfig{
+		Name:                           "billing",
+		OutputVariablesToAttributesMap: map[string]string{"customerTier": "tier"},
+		TextToSpeech:                   &config.TextToSpeechConfig{Voice: "Joanna", Engine: "neural", Language: "en-US"},
+		TransferToAgentQueues: &config.TransferToAgentQueuesConfig{
+			OutputVariable: "queue",
+			QueueArns: map[string]string{
+				"billing": "arn:aws:connect:us-east-1:111122223333:instance/instance-id/queue/billing",
+				"support": "arn:aws:connect:us-east-1:111122223333:instance/instance-id/queue/support",
+			},
+			DefaultQueueArn: "arn:aws:connect:us-east-1:111122223333:instance/instance-id/queue/default",
+		},
+	}
+
+	deployed, _, err := RenderFlowModule(template, module, resources, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extracted, _, _, err := ExtractFlowModule(deployed, template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(extracted, template) {
+		var diff bytes.Buffer
+		if err := WriteUnifiedDiff(&diff, "template", "extracted", template, extracted); err != nil {
+			t.Fatal(err)
+		}
+		t.Fatalf("extracted module differs from the template:\n%s", diff.String())
+	}
+
+	// Deploying the extracted template again must not add the transformed actions twice
+	redeployed, _, err := RenderFlowModule(extracted, module, resources, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(redeployed, deployed) {
+		t.Error("module rendered from the extracted template differs from the deployed module")
+	}
+}
@@ -0,0 +1,176 @@
+package quickstart
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// flowGraph is the action graph of a Contact Flow Module, built from the Transitions of its actions
+type flowGraph struct {
+	startAction string
+	// actions are in the order of the module
+	actions []flowAction
+	index   map[string]int
+	// actionMetadata holds the console metadata of the actions, keyed by Identifier
+	actionMetadata orderedmap.OrderedMap
+}
+
+type flowAction struct {
+	identifier string
+	actionType string
+	parameters orderedmap.OrderedMap
+	edges      []flowEdge
+}
+
+// flowEdge is a transition of an action
+type flowEdge struct {
+	target string
+	// branch is empty for the default NextAction, and holds the operands of a condition or the error type otherwise
+	branch  string
+	isError bool
+	// operator is the operator of a condition, e.g. Equals or TextContains
+	operator string
+}
+
+// buildFlowGraph builds the action graph of the module. It fails when the module does not have the shape of the
+// Amazon Connect flow language, it does not check that the transitions target existing actions.
+func buildFlowGraph(data *orderedmap.OrderedMap) (*flowGraph, error) {
+	g := &flowGraph{index: map[string]int{}, actionMetadata: newOrderedMap()}
+	var err error
+	if g.startAction, _, err = stringField(*data, "StartAction"); err != nil {
+		return nil, err
+	}
+	if metadata, ok, err := objectField(*data, "Metadata"); err != nil {
+		return nil, err
+	} else if ok {
+		actionMetadata, ok, err := objectField(metadata, "ActionMetadata")
+		if err != nil {
+			return nil, metadataError(err)
+		}
+		if ok {
+			g.actionMetadata = actionMetadata
+		}
+	}
+
+	actions, _, err := objectListField(*data, "Actions")
+	if err != nil {
+		return nil, err
+	}
+	for i, action := range actions {
+		a, err := parseFlowAction(action)
+		if err != nil {
+			return nil, fmt.Errorf("Actions[%d]: %w", i, err)
+		}
+		if _, exists := g.index[a.identifier]; exists {
+			return nil, fmt.Errorf("Actions[%d]: Identifier %s is used by more than one action", i, a.identifier)
+		}
+		g.index[a.identifier] = len(g.actions)
+		g.actions = append(g.actions, a)
+	}
+	return g, nil
+}
+
+func parseFlowAction(action orderedmap.OrderedMap) (flowAction, error) {
+	a := flowAction{parameters: newOrderedMap()}
+	var ok bool
+	var err error
+	if a.identifier, ok, err = stringField(action, "Identifier"); err != nil || !ok {
+		return a, firstError(err, "Identifier is missing")
+	}
+	if a.actionType, _, err = stringField(action, "Type"); err != nil {
+		return a, err
+	}
+	if parameters, ok, err := objectField(action, "Parameters"); err != nil {
+		return a, err
+	} else if ok {
+		a.parameters = parameters
+	}
+
+	transitions, ok, err := objectField(action, "Transitions")
+	if err != nil || !ok {
+		return a, err
+	}
+	if next, ok, err := stringField(transitions, "NextAction"); err != nil {
+		return a, err
+	} else if ok {
+		a.edges = append(a.edges, flowEdge{target: next})
+	}
+	conditions, _, err := objectListField(transitions, "Conditions")
+	if err != nil {
+		return a, err
+	}
+	for i, condition := range conditions {
+		next, ok, err := stringField(condition, "NextAction")
+		if err != nil || !ok {
+			return a, fmt.Errorf("Conditions[%d]: %w", i, firstError(err, "NextAction is missing"))
+		}
+		spec, _, _ := objectField(condition, "Condition")
+		operator, _, _ := stringField(spec, "Operator")
+		a.edges = append(a.edges, flowEdge{target: next, branch: conditionOperands(condition), operator: operator})
+	}
+	errorBranches, _, err := objectListField(transitions, "Errors")
+	if err != nil {
+		return a, err
+	}
+	for i, errorBranch := range errorBranches {
+		next, ok, err := stringField(errorBranch, "NextAction")
+		if err != nil || !ok {
+			return a, fmt.Errorf("Errors[%d]: %w", i, firstError(err, "NextAction is missing"))
+		}
+		errorType, _, err := stringField(errorBranch, "ErrorType")
+		if err != nil {
+			return a, fmt.Errorf("Errors[%d]: %w", i, err)
+		}
+		a.edges = append(a.edges, flowEdge{target: next, branch: errorType, isError: true})
+	}
+	return a, nil
+}
+
+// conditionOperands returns the operands a condition compares with, joined with commas
+func conditionOperands(condition orderedmap.OrderedMap) string {
+	spec, _, _ := objectField(condition, "Condition")
+	operands, _ := spec.Get("Operands")
+	list, _ := operands.([]any)
+	values := []string{}
+	for _, operand := range list {
+		values = append(values, fmt.Sprint(operand))
+	}
+	return strings.Join(values, ", ")
+}
+
+func firstError(err error, missing string) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%s", missing)
+}
+
+// action returns the action with the given Identifier
+func (g *flowGraph) action(identifier string) (flowAction, bool) {
+	i, ok := g.index[identifier]
+	if !ok {
+		return flowAction{}, false
+	}
+	return g.actions[i], true
+}
+
+// reachable returns the Identifiers of the actions reachable from the start action
+func (g *flowGraph) reachable() map[string]bool {
+	reached := map[string]bool{}
+	pending := []string{g.startAction}
+	for len(pending) > 0 {
+		identifier := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		action, ok := g.action(identifier)
+		if !ok || reached[identifier] {
+			continue
+		}
+		reached[identifier] = true
+		for _, edge := range action.edges {
+			pending = append(pending, edge.target)
+		}
+	}
+	return reached
+}
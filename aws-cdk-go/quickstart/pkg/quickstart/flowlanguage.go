@@ -0,0 +1,147 @@
+package quickstart
+
+import (
+	"fmt"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// Helpers to build Amazon Connect flow language blocks, see https://docs.aws.amazon.com/connect/latest/APIReference/flow-language.html
+// Blocks are built as ordered maps so they can be mixed with the blocks unmarshalled from the Contact Flow Module template.
+
+// flowError is an error branch of an action
+type flowError struct {
+	errorType  string
+	nextAction string
+}
+
+// flowCondition is a branch of a Compare action taken when the compared value equals operand
+type flowCondition struct {
+	operand    string
+	nextAction string
+}
+
+// newOrderedMap builds an ordered map from alternating string keys and values
+func newOrderedMap(keyValues ...any) orderedmap.OrderedMap {
+	m := orderedmap.New()
+	m.SetEscapeHTML(false)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		m.Set(keyValues[i].(string), keyValues[i+1])
+	}
+	return *m
+}
+
+func newFlowAction(identifier, actionType string, parameters, transitions orderedmap.OrderedMap) orderedmap.OrderedMap {
+	return newOrderedMap(
+		"Parameters", parameters,
+		"Identifier", identifier,
+		"Type", actionType,
+		"Transitions", transitions,
+	)
+}
+
+func newFlowTransitions(nextAction string, errors ...flowError) orderedmap.OrderedMap {
+	transitions := newOrderedMap()
+	if nextAction != "" {
+		transitions.Set("NextAction", nextAction)
+	}
+	if len(errors) > 0 {
+		errorsList := []any{}
+		for _, e := range errors {
+			errorsList = append(errorsList, newOrderedMap("NextAction", e.nextAction, "ErrorType", e.errorType))
+		}
+		transitions.Set("Errors", errorsList)
+	}
+	return transitions
+}
+
+// newCompareTransitions builds the transitions of a Compare action, defaultAction is taken when no condition matches
+func newCompareTransitions(defaultAction string, conditions []flowCondition) orderedmap.OrderedMap {
+	conditionsList := []any{}
+	for _, c := range conditions {
+		conditionsList = append(conditionsList, newOrderedMap(
+			"NextAction", c.nextAction,
+			"Condition", newOrderedMap("Operator", "Equals", "Operands", []any{c.operand}),
+		))
+	}
+	return newOrderedMap(
+		"NextAction", defaultAction,
+		"Conditions", conditionsList,
+		"Errors", []any{newOrderedMap("NextAction", defaultAction, "ErrorType", "NoMatchingCondition")},
+	)
+}
+
+// newActionMetadata builds the console metadata of an action, placing it at the given canvas position
+func newActionMetadata(x, y float64) orderedmap.OrderedMap {
+	return newOrderedMap(
+		"position", newOrderedMap("x", x, "y", y),
+		"isFriendlyName", true,
+	)
+}
+
+// objectField returns the object under key of m, ok is false when m has no such key
+func objectField(m orderedmap.OrderedMap, key string) (orderedmap.OrderedMap, bool, error) {
+	value, ok := m.Get(key)
+	if !ok {
+		return orderedmap.OrderedMap{}, false, nil
+	}
+	object, ok := value.(orderedmap.OrderedMap)
+	if !ok {
+		return orderedmap.OrderedMap{}, false, fmt.Errorf("%s is %s, expected an object", key, jsonTypeName(value))
+	}
+	return object, true, nil
+}
+
+// stringField returns the string under key of m, ok is false when m has no such key
+func stringField(m orderedmap.OrderedMap, key string) (string, bool, error) {
+	value, ok := m.Get(key)
+	if !ok {
+		return "", false, nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", false, fmt.Errorf("%s is %s, expected a string", key, jsonTypeName(value))
+	}
+	return str, true, nil
+}
+
+// objectListField returns the list of objects under key of m, ok is false when m has no such key
+func objectListField(m orderedmap.OrderedMap, key string) ([]orderedmap.OrderedMap, bool, error) {
+	value, ok := m.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	list, ok := value.([]any)
+	if !ok {
+		return nil, false, fmt.Errorf("%s is %s, expected a list", key, jsonTypeName(value))
+	}
+	objects := make([]orderedmap.OrderedMap, 0, len(list))
+	for i, item := range list {
+		object, ok := item.(orderedmap.OrderedMap)
+		if !ok {
+			return nil, false, fmt.Errorf("%s[%d] is %s, expected an object", key, i, jsonTypeName(item))
+		}
+		objects = append(objects, object)
+	}
+	return objects, true, nil
+}
+
+// jsonTypeName names the JSON type of a value decoded into an ordered map, for error messages
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case orderedmap.OrderedMap:
+		return "an object"
+	case []any:
+		return "a list"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("a %T", value)
+	}
+}
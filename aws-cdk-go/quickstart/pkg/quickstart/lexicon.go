@@ -0,0 +1,240 @@
+package quickstart
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/customresources"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Amazon Polly limits the size of a lexicon, see https://docs.aws.amazon.com/polly/latest/dg/limits.html
+const maxLexiconSize = 4000
+
+// plsLexicon is the root element of a W3C Pronunciation Lexicon Specification document, see https://www.w3.org/TR/pronunciation-lexicon/
+type plsLexicon struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/01/pronunciation-lexicon lexicon"`
+	Version  string      `xml:"version,attr"`
+	Alphabet string      `xml:"alphabet,attr"`
+	Language string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Lexemes  []plsLexeme `xml:"lexeme"`
+}
+
+type plsLexeme struct {
+	Graphemes []string `xml:"grapheme"`
+	Phoneme   string   `xml:"phoneme,omitempty"`
+	Alias     string   `xml:"alias,omitempty"`
+}
+
+// renderLexicon renders lexicon as a PLS document, ready to be uploaded with PutLexicon
+func renderLexicon(lexicon config.Lexicon) (string, error) {
+	pls := plsLexicon{
+		Version:  "1.0",
+		Alphabet: lexicon.Alphabet,
+		Language: lexicon.Language,
+	}
+	for _, lexeme := range lexicon.Lexemes {
+		pls.Lexemes = append(pls.Lexemes, plsLexeme{
+			Graphemes: lexeme.Graphemes,
+			Phoneme:   lexeme.Phoneme,
+			Alias:     lexeme.Alias,
+		})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(pls); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// pollyLexiconName returns the name of a lexicon in Amazon Polly. Polly names are at most 20 letters or digits and
+// shared by the account in a region, so objectPrefix cannot be prepended: the name ends with a hash of objectPrefix
+// instead, which keeps the lexicons of two deployments apart.
+func pollyLexiconName(cfg *config.Config, lexicon config.Lexicon) string {
+	hash := sha256.Sum256([]byte(cfg.ObjectPrefix))
+	return lexicon.Name + hex.EncodeToString(hash[:])[:config.LexiconNameHashLength]
+}
+
+// uploadLexicons stores the configured lexicons in Amazon Polly, in the region of the stack, under the names
+// returned by pollyLexiconName. Lexicons are deleted when the stack is deleted. Amazon Connect flows cannot pass
+// lexicons to Polly, the pullaction Lambda applies them through lexiconSSMLConversions; the stored lexicons keep the
+// pronunciations in the standard format for other Polly callers.
+func uploadLexicons(stack awscdk.Stack, cfg *config.Config, customResourceRole awsiam.IRole, customResourcesPolicy awsiam.Policy) {
+	lexiconArns := []*string{}
+	for _, lexicon := range cfg.Lexicons {
+		lexiconArns = append(lexiconArns, stack.FormatArn(&awscdk.ArnComponents{
+			Service:      jsii.String("polly"),
+			Resource:     jsii.String("lexicon"),
+			ResourceName: jsii.String(pollyLexiconName(cfg, lexicon)),
+		}))
+	}
+	customResourcesPolicy.AddStatements(
+		// Access Amazon Polly to create/delete the lexicons
+		awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions: jsii.Strings(
+				"polly:PutLexicon",
+				"polly:DeleteLexicon",
+			),
+			Resources: &lexiconArns,
+		}),
+	)
+
+	for _, lexicon := range cfg.Lexicons {
+		content, err := renderLexicon(lexicon)
+		if err != nil {
+			log.Fatalf("Failed to render lexicon %s: %v", lexicon.Name, err)
+		}
+		if len(content) > maxLexiconSize {
+			log.Fatalf("Lexicon %s is %d characters long, Amazon Polly accepts at most %d", lexicon.Name, len(content), maxLexiconSize)
+		}
+		name := pollyLexiconName(cfg, lexicon)
+		putLexiconCall := &customresources.AwsSdkCall{
+			Service: jsii.String("Polly"),
+			Action:  jsii.String("PutLexicon"),
+			Parameters: map[string]interface{}{
+				"Name":    jsii.String(name),
+				"Content": jsii.String(content),
+			},
+			PhysicalResourceId: customresources.PhysicalResourceId_Of(jsii.String(name)),
+		}
+		lexiconResource := customresources.NewAwsCustomResource(stack, generateObjectName(cfg, "lexicon-"+lexicon.Name), &customresources.AwsCustomResourceProps{
+			OnCreate: putLexiconCall,
+			OnUpdate: putLexiconCall,
+			OnDelete: &customresources.AwsSdkCall{
+				Service: jsii.String("Polly"),
+				Action:  jsii.String("DeleteLexicon"),
+				Parameters: map[string]interface{}{
+					"Name": jsii.String(name),
+				},
+			},
+			Role: customResourceRole,
+		})
+		lexiconResource.Node().AddDependency(customResourceRole, customResourcesPolicy)
+	}
+}
+
+// lexiconSSMLConversions turns the lexemes of the lexicons spoken in language, or of every lexicon when language
+// is empty, into a single SSML conversion. Amazon Connect flows cannot reference Polly lexicons, so the pullaction
+// Lambda applies the lexemes to the text it returns: graphemes are wrapped in a <phoneme> or <sub> tag carrying the
+// pronunciation from the lexicon. All graphemes are matched in one pass, each by a named group picking its
+// replacement, so a grapheme found in the alias or phoneme of another lexeme is not replaced again.
+func lexiconSSMLConversions(lexicons []config.Lexicon, language string) []config.SSMLConversion {
+	type graphemeReplacement struct {
+		grapheme    string
+		replaceWith string
+	}
+	replacements := []graphemeReplacement{}
+	for _, lexicon := range lexicons {
+		if language != "" && !strings.EqualFold(lexicon.Language, language) {
+			continue
+		}
+		for _, lexeme := range lexicon.Lexemes {
+			// $ starts a replacement pattern, the pronunciations are inserted as they are
+			var replaceWith string
+			if lexeme.Phoneme != "" {
+				replaceWith = `<phoneme alphabet="` + lexicon.Alphabet + `" ph="` + escapeReplacement(escapeXMLAttribute(lexeme.Phoneme)) + `">$&</phoneme>`
+			} else {
+				replaceWith = `<sub alias="` + escapeReplacement(escapeXMLAttribute(lexeme.Alias)) + `">$&</sub>`
+			}
+			for _, grapheme := range lexeme.Graphemes {
+				replacements = append(replacements, graphemeReplacement{grapheme, replaceWith})
+			}
+		}
+	}
+	if len(replacements) == 0 {
+		return []config.SSMLConversion{}
+	}
+
+	// JavaScript takes the first alternative that matches, longer graphemes come first so "AWS Lambda" wins over "AWS"
+	sort.SliceStable(replacements, func(i, j int) bool {
+		return utf8.RuneCountInString(replacements[i].grapheme) > utf8.RuneCountInString(replacements[j].grapheme)
+	})
+	alternatives := make([]string, 0, len(replacements))
+	replaceGroups := map[string]string{}
+	for i, replacement := range replacements {
+		name := fmt.Sprintf("lexeme%d", i)
+		alternatives = append(alternatives, "(?<"+name+">"+graphemePattern(replacement.grapheme)+")")
+		replaceGroups[name] = replacement.replaceWith
+	}
+	return []config.SSMLConversion{{
+		SearchFor:     strings.Join(alternatives, "|"),
+		ReplaceWith:   "$&",
+		ReplaceGroups: replaceGroups,
+	}}
+}
+
+// lexiconLanguage returns the language the lexicons applied to a module are selected by: the text to speech language
+// of the module, else the language of the engage request. It is empty when the language comes from the contact, in
+// which case every lexicon applies.
+func lexiconLanguage(cfg *config.Config, textToSpeech *config.TextToSpeechConfig) string {
+	if textToSpeech != nil && textToSpeech.Language != "" {
+		return textToSpeech.Language
+	}
+	if cfg.Engage.Language == config.EngageLanguageContact {
+		return ""
+	}
+	return valueOrDefault(cfg.Engage.Language, "en-US")
+}
+
+// unusedLexiconWarnings warns about the lexicons no module is spoken in
+func unusedLexiconWarnings(cfg *config.Config, modules []config.ModuleConfig) []string {
+	languages := []string{lexiconLanguage(cfg, &cfg.TextToSpeech)}
+	for _, module := range modules {
+		languages = append(languages, lexiconLanguage(cfg, module.TextToSpeech))
+	}
+	warnings := []string{}
+	for _, lexicon := range cfg.Lexicons {
+		if !slices.ContainsFunc(languages, func(language string) bool {
+			return language == "" || strings.EqualFold(language, lexicon.Language)
+		}) {
+			warnings = append(warnings, fmt.Sprintf("lexicon %s is not applied to any module, none is spoken in %s", lexicon.Name, lexicon.Language))
+		}
+	}
+	return warnings
+}
+
+// graphemePattern builds a JavaScript regular expression matching grapheme as a whole word
+func graphemePattern(grapheme string) string {
+	runes := []rune(grapheme)
+	pattern := regexp.QuoteMeta(grapheme)
+	if isWordRune(runes[0]) {
+		pattern = `\b` + pattern
+	}
+	if isWordRune(runes[len(runes)-1]) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// escapeReplacement escapes the $ of value in a replacement of an SSML conversion
+func escapeReplacement(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+func escapeXMLAttribute(value string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
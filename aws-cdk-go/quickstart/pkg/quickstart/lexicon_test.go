@@ -0,0 +1,102 @@
+package quickstart
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+)
+
+var testLexicons = []config.Lexicon{
+	{
+		Name:     "brands",
+		Language: "en-US",
+		Alphabet: "ipa",
+		Lexemes: []config.Lexeme{
+			{Graphemes: []string{"AWS"}, Alias: "Amazon Web Services"},
+			{Graphemes: []string{"Amazon"}, Phoneme: "ˈæməzɒn"},
+			{Graphemes: []string{"AWS Lambda"}, Alias: "Lambda by AWS"},
+			{Graphemes: []string{"US$"}, Alias: "US $& dollars"},
+		},
+	},
+	{
+		Name:     "marques",
+		Language: "fr-FR",
+		Alphabet: "ipa",
+		Lexemes: []config.Lexeme{
+			{Graphemes: []string{"AWS"}, Alias: "A W S"},
+		},
+	},
+}
+
+func TestLexiconSSMLConversionsApplyLexemesOnce(t *testing.T) {
+	// A grapheme found in the alias or phoneme of another lexeme must not be replaced again
+	rules, warnings, err := checkSSMLConversions(lexiconSSMLConversions(testLexicons, "en-US"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %q", warnings)
+	}
+	for _, test := range []struct {
+		text string
+		want string
+	}{
+		{"AWS", `<sub alias="Amazon Web Services">AWS</sub>`},
+		{"aws and Amazon", `<sub alias="Amazon Web Services">aws</sub> and <phoneme alphabet="ipa" ph="ˈæməzɒn">Amazon</phoneme>`},
+		{"AWS Lambda on AWS", `<sub alias="Lambda by AWS">AWS Lambda</sub> on <sub alias="Amazon Web Services">AWS</sub>`},
+		{"AWSome Amazonian", "AWSome Amazonian"},
+		{"5 US$", `5 <sub alias="US $&amp; dollars">US$</sub>`},
+	} {
+		got := applySSMLRules(rules, test.text)
+		if want := "<speak>" + test.want + "</speak>"; got != want {
+			t.Errorf("%q: got %s, want %s", test.text, got, want)
+		}
+		if err := checkSSMLFragment(strings.TrimSuffix(strings.TrimPrefix(got, "<speak>"), "</speak>")); err != nil {
+			t.Errorf("%q: %v", test.text, err)
+		}
+	}
+}
+
+func TestLexiconSSMLConversionsSelectLanguage(t *testing.T) {
+	for _, test := range []struct {
+		language string
+		text     string
+		want     string
+	}{
+		{"en-US", "AWS", `<sub alias="Amazon Web Services">AWS</sub>`},
+		{"fr-fr", "AWS", `<sub alias="A W S">AWS</sub>`},
+		{"de-DE", "AWS", "AWS"},
+		// The language of the contact is unknown at synth time, every lexicon applies and the first one wins
+		{"", "AWS", `<sub alias="Amazon Web Services">AWS</sub>`},
+	} {
+		conversions := lexiconSSMLConversions(testLexicons, test.language)
+		rules, _, err := checkSSMLConversions(conversions, len(conversions))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := applySSMLRules(rules, test.text), "<speak>"+test.want+"</speak>"; got != want {
+			t.Errorf("%q in %q: got %s, want %s", test.text, test.language, got, want)
+		}
+	}
+}
+
+func TestLexiconLanguage(t *testing.T) {
+	for _, test := range []struct {
+		engageLanguage string
+		textToSpeech   *config.TextToSpeechConfig
+		want           string
+	}{
+		{"", nil, "en-US"},
+		{"es-US", &config.TextToSpeechConfig{}, "es-US"},
+		{"es-US", &config.TextToSpeechConfig{Voice: "Lea", Engine: "neural", Language: "fr-FR"}, "fr-FR"},
+		{config.EngageLanguageContact, &config.TextToSpeechConfig{}, ""},
+		{config.EngageLanguageContact, &config.TextToSpeechConfig{Voice: "Lea", Engine: "neural", Language: "fr-FR"}, "fr-FR"},
+	} {
+		cfg := &config.Config{}
+		cfg.Engage.Language = test.engageLanguage
+		if got := lexiconLanguage(cfg, test.textToSpeech); got != test.want {
+			t.Errorf("engage %q, text to speech %+v: got %q, want %q", test.engageLanguage, test.textToSpeech, got, test.want)
+		}
+	}
+}
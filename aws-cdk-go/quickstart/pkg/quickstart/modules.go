@@ -0,0 +1,44 @@
+package quickstart
+
+import (
+	"strings"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/handoff"
+)
+
+// moduleRuntimeConfigs returns the configuration of the named modules the engage and pullaction functions select
+// with the moduleName parameter. The lexemes of the lexicons in the language of a module are applied before its
+// SSML conversions.
+func moduleRuntimeConfigs(cfg *config.Config, modules []config.ModuleConfig) map[string]runtimeConfig {
+	configs := map[string]runtimeConfig{}
+	for _, module := range modules {
+		if module.Name == "" {
+			continue
+		}
+		ssmlConversions := moduleSSMLConversions(cfg, module)
+		configs[module.Name] = newModuleRuntimeConfig(ssmlConversions, module.AttributesToInputVariablesMap, module.InputVariables)
+	}
+	return configs
+}
+
+// moduleSSMLConversions returns the conversions the pullaction function applies to the responses of a module: the
+// lexemes of the lexicons in its language, then its SSML conversions
+func moduleSSMLConversions(cfg *config.Config, module config.ModuleConfig) []config.SSMLConversion {
+	lexiconConversions := lexiconSSMLConversions(cfg.Lexicons, lexiconLanguage(cfg, module.TextToSpeech))
+	return append(lexiconConversions, module.SSMLConversions...)
+}
+
+// moduleObjectName returns the name of the Contact Flow Module, the unnamed module keeps the name used before
+// modules could be configured
+func moduleObjectName(cfg *config.Config, module config.ModuleConfig) *string {
+	if module.Name == "" {
+		return generateObjectName(cfg, "contact-flow-module")
+	}
+	return generateObjectName(cfg, "contact-flow-module-"+module.Name)
+}
+
+// moduleOutputKey returns the key of the stack output holding the ARN of a named module
+func moduleOutputKey(module config.ModuleConfig) string {
+	return handoff.OutputFlowModuleArn + strings.ToLower(module.Name)
+}
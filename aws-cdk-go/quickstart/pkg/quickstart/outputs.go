@@ -0,0 +1,45 @@
+package quickstart
+
+import (
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsssm"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// deploymentOutput is a named value exported by the stack
+type deploymentOutput struct {
+	key         string
+	description string
+	value       *string
+}
+
+func addDeploymentOutputs(stack awscdk.Stack, outputs []deploymentOutput) {
+	for _, output := range outputs {
+		awscdk.NewCfnOutput(stack, jsii.String(output.key), &awscdk.CfnOutputProps{
+			Value:       output.value,
+			Description: jsii.String(output.description),
+		})
+	}
+}
+
+// addDeploymentParameters publishes the outputs as SSM parameters named after the output key, so other stacks and
+// pipelines can look them up without cross-stack exports
+func addDeploymentParameters(stack awscdk.Stack, cfg *config.Config, outputs []deploymentOutput) {
+	path := cfg.SsmParameterPath()
+	for _, output := range outputs {
+		awsssm.NewStringParameter(stack, generateObjectName(cfg, "parameter-"+output.key), &awsssm.StringParameterProps{
+			ParameterName: jsii.String(path + output.key),
+			Description:   jsii.String(output.description),
+			StringValue:   output.value,
+			Tier:          awsssm.ParameterTier_STANDARD,
+		})
+	}
+}
+
+// resourceIdFromArn returns the last section of an Amazon Connect resource ARN token, e.g. the module ID of
+// arn:aws:connect:region:account:instance/instance-id/flow-module/module-id
+func resourceIdFromArn(resourceArn *string) *string {
+	return awscdk.Fn_Select(jsii.Number(3), awscdk.Fn_Split(jsii.String("/"), resourceArn, nil))
+}
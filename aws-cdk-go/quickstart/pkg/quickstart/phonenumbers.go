@@ -0,0 +1,94 @@
+package quickstart
+
+import (
+	"strings"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsconnect"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/customresources"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// phoneNumber is a phone number associated with the contact flow, named by its ID in construct IDs
+type phoneNumber struct {
+	name string
+	arn  *string
+	id   *string
+}
+
+// associatePhoneNumbers points the configured phone numbers, and the claimed one if any, at contactFlowArn. The
+// numbers are disassociated from the flow when the stack is deleted. It returns the claimed phone number, or nil
+// when no number is claimed.
+func associatePhoneNumbers(stack awscdk.Stack, cfg *config.Config, contactFlowArn *string, customResourceRole awsiam.IRole, customResourcesPolicy awsiam.Policy) awsconnect.CfnPhoneNumber {
+	customResourcesPolicy.AddStatements(
+		// Access Amazon Connect to associate/disassociate phone numbers and flows
+		awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions: jsii.Strings(
+				"connect:AssociatePhoneNumberContactFlow",
+				"connect:DisassociatePhoneNumberContactFlow",
+			),
+			Resources: jsii.Strings("*"),
+		}),
+	)
+
+	// In config order, so the resources keep their order in the synthesized template
+	phoneNumbers := []phoneNumber{}
+	for _, phoneNumberArn := range cfg.PhoneNumbers.Arns {
+		// arn:aws:connect:region:account:phone-number/phone-number-id
+		phoneNumberId := phoneNumberArn[strings.LastIndex(phoneNumberArn, "/")+1:]
+		phoneNumbers = append(phoneNumbers, phoneNumber{name: phoneNumberId, arn: jsii.String(phoneNumberArn), id: jsii.String(phoneNumberId)})
+	}
+
+	var claimedPhoneNumber awsconnect.CfnPhoneNumber
+	if cfg.PhoneNumbers.Claim.CountryCode != "" {
+		claimedPhoneNumberProps := &awsconnect.CfnPhoneNumberProps{
+			TargetArn:   jsii.String(cfg.ConnectInstanceArn),
+			CountryCode: jsii.String(cfg.PhoneNumbers.Claim.CountryCode),
+			Type:        jsii.String(cfg.PhoneNumbers.Claim.Type),
+			Description: jsii.String("Phone number claimed for the ASAPP GenerativeAgent quickstart"),
+		}
+		if cfg.PhoneNumbers.Claim.Prefix != "" {
+			claimedPhoneNumberProps.Prefix = jsii.String(cfg.PhoneNumbers.Claim.Prefix)
+		}
+		claimedPhoneNumber = awsconnect.NewCfnPhoneNumber(stack, generateObjectName(cfg, "phone-number"), claimedPhoneNumberProps)
+		// The ARN of the claimed number is only known at deployment time
+		claimedPhoneNumberArn := claimedPhoneNumber.AttrPhoneNumberArn()
+		phoneNumbers = append(phoneNumbers, phoneNumber{
+			name: "claimed",
+			arn:  claimedPhoneNumberArn,
+			id:   awscdk.Fn_Select(jsii.Number(1), awscdk.Fn_Split(jsii.String("/"), claimedPhoneNumberArn, nil)),
+		})
+	}
+
+	contactFlowId := resourceIdFromArn(contactFlowArn)
+	for _, number := range phoneNumbers {
+		associateCall := &customresources.AwsSdkCall{
+			Service: jsii.String("Connect"),
+			Action:  jsii.String("AssociatePhoneNumberContactFlow"),
+			Parameters: map[string]interface{}{
+				"InstanceId":    jsii.String(cfg.ConnectInstanceArn),
+				"PhoneNumberId": number.id,
+				"ContactFlowId": contactFlowId,
+			},
+			PhysicalResourceId: customresources.PhysicalResourceId_Of(number.arn),
+		}
+		association := customresources.NewAwsCustomResource(stack, generateObjectName(cfg, "phone-number-association-"+number.name), &customresources.AwsCustomResourceProps{
+			OnCreate: associateCall,
+			OnUpdate: associateCall,
+			OnDelete: &customresources.AwsSdkCall{
+				Service: jsii.String("Connect"),
+				Action:  jsii.String("DisassociatePhoneNumberContactFlow"),
+				Parameters: map[string]interface{}{
+					"InstanceId":    jsii.String(cfg.ConnectInstanceArn),
+					"PhoneNumberId": number.id,
+				},
+			},
+			Role: customResourceRole,
+		})
+		association.Node().AddDependency(customResourceRole, customResourcesPolicy)
+	}
+	return claimedPhoneNumber
+}
@@ -0,0 +1,226 @@
+package quickstart
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/iancoleman/orderedmap"
+)
+
+// placeholderPattern matches the placeholders of the flow module template, a name optionally typed by a kind:
+// {{partition}}, {{prompt:Wait1s}} or {{lambda:Engage}}
+var placeholderPattern = regexp.MustCompile(`\{\{([a-zA-Z]+(?::[A-Za-z0-9_-]+)?)\}\}`)
+
+// Placeholders of the flow module template
+const (
+	placeholderPartition  = "partition"
+	placeholderPrompt     = "prompt:"
+	placeholderLambda     = "lambda:"
+	placeholderLambdaName = "lambdaName:"
+)
+
+// Names of the prompts and Lambda functions in the placeholders of the flow module template
+const (
+	promptWait1s     = "Wait1s"
+	promptWait400ms  = "Wait400ms"
+	promptBeepBop    = "BeepBop"
+	lambdaEngage     = "Engage"
+	lambdaPullAction = "PullAction"
+)
+
+// ResolvePlaceholders replaces the placeholders in the strings of the module with their values, keyed by the text
+// between the braces, e.g. prompt:Wait1s. It fails on placeholders that have no value, on braces that are not a
+// placeholder, and on values that are not used by the module, which are a sign that the template and the stack do
+// not match.
+func ResolvePlaceholders(data *orderedmap.OrderedMap, values map[string]string) ([]string, error) {
+	used := map[string]bool{}
+	changes := []string{}
+	if err := resolvePlaceholders(*data, "", values, used, &changes); err != nil {
+		return nil, err
+	}
+
+	unused := []string{}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if !used[name] {
+			unused = append(unused, "{{"+name+"}}")
+		}
+	}
+	if len(unused) > 0 {
+		return nil, fmt.Errorf("the module does not use the placeholders %s", strings.Join(unused, ", "))
+	}
+	return changes, nil
+}
+
+func resolvePlaceholders(value any, path string, values map[string]string, used map[string]bool, changes *[]string) error {
+	resolve := func(s, path string) (string, error) {
+		var unknown []string
+		resolved := placeholderPattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+			name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+			value, ok := values[name]
+			if !ok {
+				unknown = append(unknown, placeholder)
+				return placeholder
+			}
+			used[name] = true
+			return value
+		})
+		if len(unknown) > 0 {
+			return "", fmt.Errorf("%s: unknown placeholder %s", path, strings.Join(unknown, ", "))
+		}
+		if strings.Contains(placeholderPattern.ReplaceAllString(s, ""), "{{") {
+			return "", fmt.Errorf("%s: %q has braces that are not a placeholder", path, s)
+		}
+		if resolved != s {
+			*changes = append(*changes, fmt.Sprintf("set %s to %s", path, resolved))
+		}
+		return resolved, nil
+	}
+
+	switch v := value.(type) {
+	case orderedmap.OrderedMap:
+		for _, key := range v.Keys() {
+			nested, _ := v.Get(key)
+			nestedPath := strings.TrimPrefix(path+"."+key, ".")
+			if s, ok := nested.(string); ok {
+				resolved, err := resolve(s, nestedPath)
+				if err != nil {
+					return err
+				}
+				v.Set(key, resolved)
+				continue
+			}
+			if err := resolvePlaceholders(nested, nestedPath, values, used, changes); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, item := range v {
+			itemPath := listItemPath(path, i, item)
+			if s, ok := item.(string); ok {
+				resolved, err := resolve(s, itemPath)
+				if err != nil {
+					return err
+				}
+				v[i] = resolved
+				continue
+			}
+			if err := resolvePlaceholders(item, itemPath, values, used, changes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listItemPath returns the path of the i-th item of a list in messages, actions are named by their Identifier
+func listItemPath(path string, i int, item any) string {
+	if object, ok := item.(orderedmap.OrderedMap); ok {
+		if identifier, ok, _ := stringField(object, "Identifier"); ok {
+			return fmt.Sprintf("%s[%s]", path, identifier)
+		}
+	}
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+// Values of the flow module template before it had placeholders, replaced by ConvertToPlaceholders
+var (
+	legacyPromptActions = map[string]string{
+		"Wait1sPrompt":     promptWait1s,
+		"Wait400msPrompt":  promptWait400ms,
+		"PlayBeepBopShort": promptBeepBop,
+	}
+	legacyLambdaActions = map[string]string{
+		"Engage":     lambdaEngage,
+		"PullAction": lambdaPullAction,
+	}
+	legacyLambdaDisplayNames = map[string]string{
+		"generativeagent-quickstart-lambda-genagent-engage": lambdaEngage,
+		"generativeagent-quickstart-lambda-pullaction":      lambdaPullAction,
+	}
+)
+
+// ConvertToPlaceholders converts a flow module template written for the former UpdateResourcesARN to placeholders:
+// the PromptId and LambdaFunctionARN of the actions it recognised by their Identifier, the display names of the
+// Lambda functions, and the aws partition of ARNs become placeholders. It returns the template formatted like the one
+// in the repository, and the changes made.
+func ConvertToPlaceholders(template []byte) ([]byte, []string, error) {
+	module, err := unmarshalFlowModule(template)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes := []string{}
+
+	actions, _, err := objectListField(module, "Actions")
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, action := range actions {
+		identifier, _, err := stringField(action, "Identifier")
+		if err != nil {
+			return nil, nil, fmt.Errorf("Actions[%d]: %w", i, err)
+		}
+		parameter, placeholder := "", ""
+		if prompt, ok := legacyPromptActions[identifier]; ok {
+			parameter, placeholder = "PromptId", "{{"+placeholderPrompt+prompt+"}}"
+		} else if lambda, ok := legacyLambdaActions[identifier]; ok {
+			parameter, placeholder = "LambdaFunctionARN", "{{"+placeholderLambda+lambda+"}}"
+		} else {
+			continue
+		}
+		if err := setParameter(&action, parameter, placeholder); err != nil {
+			return nil, nil, actionError(identifier, err)
+		}
+		changes = append(changes, fmt.Sprintf("set %s of %s to %s", parameter, identifier, placeholder))
+	}
+
+	convertLegacyStrings(module, "", &changes)
+	content, err := marshalFlowModule(module)
+	return content, changes, err
+}
+
+// convertLegacyStrings replaces the display names of the Lambda functions and the aws partition of ARNs with
+// placeholders
+func convertLegacyStrings(value any, path string, changes *[]string) {
+	convert := func(key, s string) string {
+		if lambda, ok := legacyLambdaDisplayNames[s]; ok && key == "displayName" {
+			return "{{" + placeholderLambdaName + lambda + "}}"
+		}
+		// Parameters such as the Compare of CheckIfMediaStreamingStarted only hold the start of an ARN
+		if s == "arn:aws" || (arn.IsARN(s) && strings.HasPrefix(s, "arn:aws:")) {
+			return "arn:{{" + placeholderPartition + "}}" + strings.TrimPrefix(s, "arn:aws")
+		}
+		return s
+	}
+
+	switch v := value.(type) {
+	case orderedmap.OrderedMap:
+		for _, key := range v.Keys() {
+			nested, _ := v.Get(key)
+			nestedPath := strings.TrimPrefix(path+"."+key, ".")
+			if s, ok := nested.(string); ok {
+				if converted := convert(key, s); converted != s {
+					v.Set(key, converted)
+					*changes = append(*changes, fmt.Sprintf("set %s to %s", nestedPath, converted))
+				}
+				continue
+			}
+			convertLegacyStrings(nested, nestedPath, changes)
+		}
+	case []any:
+		for i, item := range v {
+			itemPath := listItemPath(path, i, item)
+			if s, ok := item.(string); ok {
+				if converted := convert("", s); converted != s {
+					v[i] = converted
+					*changes = append(*changes, fmt.Sprintf("set %s to %s", itemPath, converted))
+				}
+				continue
+			}
+			convertLegacyStrings(item, itemPath, changes)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package quickstart
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/iancoleman/orderedmap"
+)
+
+// ModuleResources are the resources of an Amazon Connect instance a Contact Flow Module is rendered for, when it is
+// installed without the stack
+type ModuleResources struct {
+	ConnectInstanceArn  string
+	EngageLambdaArn     string
+	PullActionLambdaArn string
+	// PromptIds are the IDs or ARNs of the prompts uploaded from flow-modules/prompts, keyed by their name in the
+	// placeholders of the template: Wait1s, Wait400ms and BeepBop
+	PromptIds map[string]string
+}
+
+// RenderFlowModule builds a Contact Flow Module from the flow module template with the same transforms as the
+// stack, for the resources of an instance that was set up by hand. ssml is set when the PullAction function has SSML
+// conversions. It returns the module, ready to be imported in the Amazon Connect console, and the changes made.
+func RenderFlowModule(template []byte, module config.ModuleConfig, resources ModuleResources, ssml bool) ([]byte, []TransformReport, error) {
+	instanceArn, err := arn.Parse(resources.ConnectInstanceArn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect instance ARN: %w", err)
+	}
+	instanceId, err := connectInstanceId(resources.ConnectInstanceArn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := config.ValidateRegionPartition(instanceArn.Region, instanceArn.Partition); err != nil {
+		return nil, nil, fmt.Errorf("connect instance ARN: %w", err)
+	}
+	cfg := &config.Config{
+		Region:             instanceArn.Region,
+		AccountId:          instanceArn.AccountID,
+		ConnectInstanceArn: resources.ConnectInstanceArn,
+		Modules:            []config.ModuleConfig{module},
+	}
+	// Settings the module does not set are left at their defaults
+	module = cfg.FlowModules()[0]
+
+	moduleResources := moduleResources{
+		partition:   cfg.Partition(),
+		promptArns:  map[string]string{},
+		lambdaArns:  map[string]string{},
+		lambdaNames: map[string]string{},
+	}
+	for name, promptId := range resources.PromptIds {
+		if !arn.IsARN(promptId) {
+			promptId = connectPromptArn(cfg.Partition(), cfg.Region, cfg.AccountId, instanceId, promptId)
+		}
+		moduleResources.promptArns[name] = promptId
+	}
+	for name, functionArn := range map[string]string{
+		lambdaEngage:     resources.EngageLambdaArn,
+		lambdaPullAction: resources.PullActionLambdaArn,
+	} {
+		functionName, err := lambdaFunctionName(functionArn)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s function: %w", name, err)
+		}
+		moduleResources.lambdaArns[name] = functionArn
+		moduleResources.lambdaNames[name] = functionName
+	}
+
+	content, reports, err := buildFlowModule(template, cfg, module, moduleResources, ssml, nil)
+	if err != nil {
+		return nil, reports, err
+	}
+	rendered, err := json.MarshalIndent(content, "", "  ")
+	return rendered, reports, err
+}
+
+// buildFlowModule runs the built-in transforms and then transforms on the flow module template, and checks the
+// result with ValidateModule
+func buildFlowModule(template []byte, cfg *config.Config, module config.ModuleConfig, resources moduleResources, ssml bool,
+	transforms []ModuleTransform) (*orderedmap.OrderedMap, []TransformReport, error) {
+	var content orderedmap.OrderedMap
+	if err := json.Unmarshal(template, &content); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal flow module template: %w", err)
+	}
+
+	pipeline := &ModulePipeline{}
+	pipeline.Register(builtinModuleTransforms(cfg, module, resources, ssml)...)
+	pipeline.Register(transforms...)
+	reports, err := pipeline.Apply(FlowModule{Name: module.Name, Content: &content})
+	if err != nil {
+		return nil, reports, err
+	}
+
+	// Check the module here, a broken transition would otherwise only surface as an Amazon Connect import error
+	if err := ValidateModule(&content); err != nil {
+		return nil, reports, fmt.Errorf("invalid module:\n%w", err)
+	}
+	return &content, reports, nil
+}
+
+// connectInstanceId returns the ID of the Amazon Connect instance of an instance ARN
+func connectInstanceId(connectInstanceArn string) (string, error) {
+	parsed, err := arn.Parse(connectInstanceArn)
+	if err != nil {
+		return "", err
+	}
+	resourceArnSection := strings.Split(parsed.Resource, "/")
+	if len(resourceArnSection) != 2 || resourceArnSection[0] != "instance" {
+		return "", fmt.Errorf("%s is not the ARN of an Amazon Connect instance", connectInstanceArn)
+	}
+	return resourceArnSection[1], nil
+}
+
+// connectPromptArn returns the ARN of a prompt of an Amazon Connect instance
+func connectPromptArn(partition, region, accountId, instanceId, promptId string) string {
+	return fmt.Sprintf("arn:%s:connect:%s:%s:instance/%s/prompt/%s", partition, region, accountId, instanceId, promptId)
+}
+
+// lambdaFunctionName returns the name of the function of a Lambda function or alias ARN, with the alias if any
+func lambdaFunctionName(functionArn string) (string, error) {
+	parsed, err := arn.Parse(functionArn)
+	if err != nil {
+		return "", err
+	}
+	name, ok := strings.CutPrefix(parsed.Resource, "function:")
+	if parsed.Service != "lambda" || !ok {
+		return "", fmt.Errorf("%s is not the ARN of a Lambda function", functionArn)
+	}
+	return name, nil
+}
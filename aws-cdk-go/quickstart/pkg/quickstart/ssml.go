@@ -0,0 +1,458 @@
+package quickstart
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+)
+
+// SSML tags supported by Amazon Polly, see https://docs.aws.amazon.com/polly/latest/dg/supportedtags.html
+var pollySSMLTags = map[string]bool{
+	"break":               true,
+	"emphasis":            true,
+	"lang":                true,
+	"mark":                true,
+	"p":                   true,
+	"phoneme":             true,
+	"prosody":             true,
+	"s":                   true,
+	"say-as":              true,
+	"sub":                 true,
+	"w":                   true,
+	"amazon:auto-breaths": true,
+	"amazon:breath":       true,
+	"amazon:domain":       true,
+	"amazon:effect":       true,
+}
+
+// ssmlRule is an SSML conversion checked against the way the pullaction Lambda applies it: searchFor is compiled
+// as a JavaScript RegExp with the gi flags and the matches are replaced with String.replaceAll().
+type ssmlRule struct {
+	conversion config.SSMLConversion
+	// regexp is the Go equivalent of searchFor, nil when searchFor uses JavaScript constructs RE2 does not support
+	regexp *regexp.Regexp
+}
+
+// checkSSMLConversions checks the SSML conversions before they are deployed. It returns an error for rules the
+// pullaction Lambda cannot apply as intended, and warnings for rules that could only be partially checked. The
+// conversions before configuredFrom are generated from lexicons, the others come from ssmlConversions.
+func checkSSMLConversions(conversions []config.SSMLConversion, configuredFrom int) ([]ssmlRule, []string, error) {
+	rules := []ssmlRule{}
+	names := []string{}
+	warnings := []string{}
+	var errs []error
+	for i, conversion := range conversions {
+		name := fmt.Sprintf("ssmlConversions[%d]", i-configuredFrom)
+		if i < configuredFrom {
+			name = "lexicon conversion"
+		}
+		names = append(names, name)
+		rule := ssmlRule{conversion: conversion}
+		goPattern, jsOnly, err := translateJSPattern(conversion.SearchFor)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s.searchFor %q: %v", name, conversion.SearchFor, err))
+		} else if jsOnly != "" {
+			warnings = append(warnings, fmt.Sprintf("%s.searchFor %q uses %s, which Go's RE2 does not support: the rule is not compiled nor previewed at synth time", name, conversion.SearchFor, jsOnly))
+		} else if rule.regexp, err = regexp.Compile("(?i)" + goPattern); err != nil {
+			errs = append(errs, fmt.Errorf("%s.searchFor %q does not compile: %v", name, conversion.SearchFor, err))
+		}
+
+		// Replacement patterns such as $& are replaced with matched text, they are not part of the markup
+		replacementPattern := jsReplacementPattern
+		if len(conversion.ReplaceGroups) != 0 {
+			replacementPattern = groupReplacementPattern
+		}
+		if err := checkSSMLFragment(replacementPattern.ReplaceAllLiteralString(conversion.ReplaceWith, "x")); err != nil {
+			errs = append(errs, fmt.Errorf("%s.replaceWith %q: %v", name, conversion.ReplaceWith, err))
+		}
+		for _, group := range slices.Sorted(maps.Keys(conversion.ReplaceGroups)) {
+			replaceWith := conversion.ReplaceGroups[group]
+			if rule.regexp != nil && rule.regexp.SubexpIndex(group) < 0 {
+				errs = append(errs, fmt.Errorf("%s.replaceGroups: searchFor has no group named %s", name, group))
+			}
+			if err := checkSSMLFragment(groupReplacementPattern.ReplaceAllLiteralString(replaceWith, "x")); err != nil {
+				errs = append(errs, fmt.Errorf("%s.replaceGroups.%s %q: %v", name, group, replaceWith, err))
+			}
+		}
+		if rule.regexp != nil && len(conversion.ReplaceGroups) == 0 {
+			for _, ref := range unknownGroupReferences(conversion.ReplaceWith, rule.regexp) {
+				warnings = append(warnings, fmt.Sprintf("%s.replaceWith %q refers to %s, which searchFor does not capture: it is not replaced with a group", name, conversion.ReplaceWith, ref))
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if len(errs) != 0 {
+		return rules, warnings, errors.Join(errs...)
+	}
+
+	// Later rules see the markup of earlier ones, such as a grapheme in the alias of a lexeme: run the whole chain on
+	// the text each rule is written for
+	for i, rule := range rules {
+		for _, literal := range literalMatches(rule.conversion.SearchFor) {
+			if rule.regexp == nil || !rule.regexp.MatchString(literal) {
+				continue
+			}
+			ssml := applySSMLRules(rules, literal)
+			if err := checkSSMLFragment(strings.TrimSuffix(strings.TrimPrefix(ssml, "<speak>"), "</speak>")); err != nil {
+				errs = append(errs, fmt.Errorf("%s: the conversions turn %q into %s: %v", names[i], literal, ssml, err))
+			}
+		}
+	}
+	return rules, warnings, errors.Join(errs...)
+}
+
+// literalMatches returns the text matched by each alternative of searchFor written with plain characters, escaped
+// punctuation, anchors and word boundaries only, such as the graphemes of the lexicon conversion
+func literalMatches(searchFor string) []string {
+	literals := []string{}
+	for _, alternative := range splitAlternatives(searchFor) {
+		if literal, ok := literalText(alternative); ok {
+			literals = append(literals, literal)
+		}
+	}
+	return literals
+}
+
+// splitAlternatives splits pattern on the | outside groups and character classes
+func splitAlternatives(pattern string) []string {
+	alternatives := []string{}
+	depth, start, inClass := 0, 0, false
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			alternatives = append(alternatives, pattern[start:i])
+			start = i + 1
+		}
+	}
+	return append(alternatives, pattern[start:])
+}
+
+// groupWrapper matches an alternative made of one group
+var groupWrapper = regexp.MustCompile(`^\((?:\?:|\?<[A-Za-z_$][\w$]*>)?(.*)\)$`)
+
+// literalText returns the text alternative matches when it is a literal, a group around one is unwrapped
+func literalText(alternative string) (string, bool) {
+	if match := groupWrapper.FindStringSubmatch(alternative); match != nil {
+		alternative = match[1]
+	}
+	alternative = strings.TrimPrefix(strings.TrimPrefix(alternative, "^"), `\b`)
+	if trimmed, ok := strings.CutSuffix(alternative, `\b`); ok && !strings.HasSuffix(trimmed, `\`) {
+		alternative = trimmed
+	}
+	if trimmed, ok := strings.CutSuffix(alternative, "$"); ok && !strings.HasSuffix(trimmed, `\`) {
+		alternative = trimmed
+	}
+	var literal strings.Builder
+	for i := 0; i < len(alternative); i++ {
+		c := alternative[i]
+		switch {
+		case c == '\\':
+			// Escaped letters and digits are classes, backreferences or control characters
+			if i+1 == len(alternative) || isWordRune(rune(alternative[i+1])) {
+				return "", false
+			}
+			i++
+			literal.WriteByte(alternative[i])
+		case strings.IndexByte(".*+?()[]{}|^$", c) >= 0:
+			return "", false
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	return literal.String(), literal.Len() != 0
+}
+
+// jsWhitespace holds the characters JavaScript's \s matches, the WhiteSpace and LineTerminator characters of the
+// ECMAScript specification. RE2's \s only matches ASCII whitespace.
+const jsWhitespace = `\t\n\v\f\r \x{a0}\x{1680}\x{2000}-\x{200a}\x{2028}\x{2029}\x{202f}\x{205f}\x{3000}\x{feff}`
+
+// jsDot is the class JavaScript's . matches without the s flag, RE2's . also matches \r and the Unicode line
+// terminators
+const jsDot = `[^\n\r\x{2028}\x{2029}]`
+
+// translateJSPattern translates a JavaScript regular expression, as compiled without the u flag, to RE2 syntax. It
+// returns an error for RE2 constructs JavaScript reads differently, and names the first JavaScript construct RE2
+// has no equivalent for in jsOnly. \s, \S and . are spelled out as classes, as RE2 gives them other characters.
+func translateJSPattern(pattern string) (goPattern string, jsOnly string, err error) {
+	if pattern == "" {
+		return "", "", fmt.Errorf("an empty pattern matches between every character")
+	}
+	var out strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		rest := pattern[i:]
+		switch {
+		case c == '\\':
+			if i+1 == len(pattern) {
+				return "", "", fmt.Errorf("trailing backslash")
+			}
+			next := pattern[i+1]
+			switch {
+			case next >= '1' && next <= '9' && !inClass:
+				jsOnly = firstOf(jsOnly, "a backreference")
+			case next == 'k' && strings.HasPrefix(rest, `\k<`):
+				jsOnly = firstOf(jsOnly, "a named backreference")
+			case strings.ContainsRune("AzZQEC", rune(next)):
+				return "", "", fmt.Errorf(`\%c is an RE2 escape, JavaScript matches the letter %c instead`, next, next)
+			case next == 'p' || next == 'P':
+				return "", "", fmt.Errorf(`\%c needs the u flag in JavaScript, which the pullaction Lambda does not set`, next)
+			case next == 'x' && strings.HasPrefix(rest, `\x{`):
+				return "", "", fmt.Errorf(`\x{...} is RE2 syntax, use \uXXXX in JavaScript`)
+			case next == 'u' && len(rest) >= 6 && isHex(rest[2:6]):
+				out.WriteString(`\x{` + rest[2:6] + `}`)
+				i += 5
+				continue
+			case next == 's':
+				if inClass {
+					out.WriteString(jsWhitespace)
+				} else {
+					out.WriteString("[" + jsWhitespace + "]")
+				}
+				i++
+				continue
+			case next == 'S':
+				if inClass {
+					// RE2 cannot add the complement of a class to another class
+					jsOnly = firstOf(jsOnly, `\S inside a character class`)
+				} else {
+					out.WriteString("[^" + jsWhitespace + "]")
+					i++
+					continue
+				}
+			}
+			out.WriteString(rest[:2])
+			i++
+			continue
+		case inClass:
+			if strings.HasPrefix(rest, "[:") {
+				return "", "", fmt.Errorf("POSIX character classes are RE2 syntax, JavaScript matches the characters of the class instead")
+			}
+			if c == ']' {
+				inClass = false
+			}
+		case c == '.':
+			out.WriteString(jsDot)
+			continue
+		case c == '[':
+			if strings.HasPrefix(rest, "[]") || strings.HasPrefix(rest, "[^]") {
+				return "", "", fmt.Errorf("[] and [^] match nothing and anything in JavaScript, but start a class holding ] in RE2")
+			}
+			inClass = true
+		case c == '(' && strings.HasPrefix(rest, "(?"):
+			switch {
+			case strings.HasPrefix(rest, "(?:"):
+			case strings.HasPrefix(rest, "(?=") || strings.HasPrefix(rest, "(?!"):
+				jsOnly = firstOf(jsOnly, "a lookahead")
+			case strings.HasPrefix(rest, "(?<=") || strings.HasPrefix(rest, "(?<!"):
+				jsOnly = firstOf(jsOnly, "a lookbehind")
+			case strings.HasPrefix(rest, "(?<"):
+			case strings.HasPrefix(rest, "(?P<"):
+				return "", "", fmt.Errorf("(?P<name>...) is RE2 syntax, use (?<name>...) in JavaScript")
+			default:
+				return "", "", fmt.Errorf("inline flags are RE2 syntax, the pullaction Lambda always matches with the gi flags")
+			}
+		}
+		out.WriteByte(c)
+	}
+	return out.String(), jsOnly, nil
+}
+
+func firstOf(current, construct string) string {
+	if current != "" {
+		return current
+	}
+	return construct
+}
+
+func isHex(s string) bool {
+	_, err := strconv.ParseUint(s, 16, 16)
+	return err == nil
+}
+
+// checkSSMLFragment checks that fragment is well-formed SSML holding only tags supported by Amazon Polly. The
+// pullaction Lambda adds the surrounding <speak> tags.
+func checkSSMLFragment(fragment string) error {
+	decoder := xml.NewDecoder(strings.NewReader("<speak>" + fragment + "</speak>"))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("not well-formed SSML: %v", err)
+		}
+		if element, ok := token.(xml.StartElement); ok {
+			depth++
+			name := element.Name.Local
+			if element.Name.Space != "" {
+				name = element.Name.Space + ":" + name
+			}
+			if depth > 1 && !pollySSMLTags[name] {
+				return fmt.Errorf("<%s> is not an SSML tag supported by Amazon Polly", name)
+			}
+		}
+	}
+}
+
+// jsReplacementPattern matches the special replacement patterns of String.replaceAll()
+var jsReplacementPattern = regexp.MustCompile(`\$(\$|&|` + "`" + `|'|[0-9]{1,2}|<[^>]*>)`)
+
+// groupReplacementPattern matches the replacement patterns the pullaction Lambda expands in the replacements of a
+// conversion with replaceGroups
+var groupReplacementPattern = regexp.MustCompile(`\$[$&]`)
+
+// unknownGroupReferences returns the $n and $<name> references of replaceWith that searchFor does not capture
+func unknownGroupReferences(replaceWith string, searchFor *regexp.Regexp) []string {
+	unknown := []string{}
+	for _, match := range jsReplacementPattern.FindAllStringSubmatch(replaceWith, -1) {
+		token := match[1]
+		switch {
+		case token[0] == '<':
+			if searchFor.SubexpIndex(token[1:len(token)-1]) < 0 {
+				unknown = append(unknown, match[0])
+			}
+		case token[0] >= '0' && token[0] <= '9':
+			if _, _, ok := jsGroupNumber(token, searchFor.NumSubexp()); !ok {
+				unknown = append(unknown, match[0])
+			}
+		}
+	}
+	return unknown
+}
+
+// jsGroupNumber resolves the digits of a $n or $nn replacement pattern the way String.replaceAll() does: $nn refers
+// to group nn when the pattern has that many groups, else to group n followed by the literal second digit. ok is
+// false when the pattern refers to no group and is inserted literally.
+func jsGroupNumber(digits string, numSubexp int) (n int, literal string, ok bool) {
+	if n, _ := strconv.Atoi(digits); n > 0 && n <= numSubexp {
+		return n, "", true
+	}
+	if n := int(digits[0] - '0'); len(digits) == 2 && n > 0 && n <= numSubexp {
+		return n, digits[1:], true
+	}
+	return 0, "", false
+}
+
+// applySSMLRules applies the rules to text the way the pullaction Lambda does. Rules that could not be compiled
+// in Go are skipped.
+func applySSMLRules(rules []ssmlRule, text string) string {
+	for _, rule := range rules {
+		if rule.regexp == nil {
+			continue
+		}
+		if len(rule.conversion.ReplaceGroups) != 0 {
+			text = replaceGroupsJS(rule.regexp, text, rule.conversion.ReplaceWith, rule.conversion.ReplaceGroups)
+		} else {
+			text = replaceAllJS(rule.regexp, text, rule.conversion.ReplaceWith)
+		}
+	}
+	return "<speak>" + text + "</speak>"
+}
+
+// replaceAllJS replaces the matches of re in text, expanding the replacement patterns of String.replaceAll()
+func replaceAllJS(re *regexp.Regexp, text, replaceWith string) string {
+	var out strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(text, -1) {
+		out.WriteString(text[last:match[0]])
+		out.WriteString(jsReplacementPattern.ReplaceAllStringFunc(replaceWith, func(token string) string {
+			group := func(n int) string {
+				if n < 0 || n > re.NumSubexp() || match[2*n] < 0 {
+					return ""
+				}
+				return text[match[2*n]:match[2*n+1]]
+			}
+			switch t := token[1:]; {
+			case t == "$":
+				return "$"
+			case t == "&":
+				return group(0)
+			case t == "`":
+				return text[:match[0]]
+			case t == "'":
+				return text[match[1]:]
+			case t[0] == '<':
+				if n := re.SubexpIndex(t[1 : len(t)-1]); n > 0 {
+					return group(n)
+				}
+				// Unknown names are only inserted literally when the pattern has no named group
+				if slices.ContainsFunc(re.SubexpNames(), func(name string) bool { return name != "" }) {
+					return ""
+				}
+				return token
+			default:
+				if n, literal, ok := jsGroupNumber(t, re.NumSubexp()); ok {
+					return group(n) + literal
+				}
+				return token
+			}
+		}))
+		last = match[1]
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// replaceGroupsJS replaces the matches of re in text with the replacement of the named group that took part in the
+// match, or replaceWith, the way the pullaction Lambda applies a conversion with replaceGroups
+func replaceGroupsJS(re *regexp.Regexp, text, replaceWith string, replaceGroups map[string]string) string {
+	// The Lambda looks the groups up in the key order of the JSON object, which encoding/json sorts
+	groups := slices.Sorted(maps.Keys(replaceGroups))
+	var out strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(text, -1) {
+		out.WriteString(text[last:match[0]])
+		replacement := replaceWith
+		for _, group := range groups {
+			if n := re.SubexpIndex(group); n > 0 && match[2*n] >= 0 {
+				replacement = replaceGroups[group]
+				break
+			}
+		}
+		out.WriteString(groupReplacementPattern.ReplaceAllStringFunc(replacement, func(token string) string {
+			if token == "$$" {
+				return "$"
+			}
+			return text[match[0]:match[1]]
+		}))
+		last = match[1]
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// previewSSMLConversions prints the result of the rules applied to each sample sentence under title, and warns when
+// the result is not well-formed SSML.
+func previewSSMLConversions(title string, rules []ssmlRule, samples []string) {
+	if len(samples) == 0 {
+		return
+	}
+	fmt.Printf("%s preview:\n", title)
+	for _, sample := range samples {
+		ssml := applySSMLRules(rules, sample)
+		fmt.Printf("  %s\n  => %s\n", sample, ssml)
+		if err := checkSSMLFragment(strings.TrimSuffix(strings.TrimPrefix(ssml, "<speak>"), "</speak>")); err != nil {
+			fmt.Printf("  Warning: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package quickstart
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+)
+
+func TestTranslateJSPatternMatchesLikeJavaScript(t *testing.T) {
+	// Expected results are those of new RegExp(pattern, "gi").test(input) in Node.js
+	for _, test := range []struct {
+		pattern string
+		input   string
+		match   bool
+	}{
+		{`a\sb`, "a b", true},
+		{`a\sb`, "a\u00a0b", true},
+		{`a\sb`, "a\u2028b", true},
+		{`a\sb`, "a\u3000b", true},
+		{`a\sb`, "a\ufeffb", true},
+		{`a\sb`, "a\u200bb", false},
+		{`a[\s-]b`, "a\u00a0b", true},
+		{`a[\s-]b`, "a-b", true},
+		{`^\S+$`, "ASAPP", true},
+		{`^\S+$`, "AS\u00a0APP", false},
+		{`a.b`, "a-b", true},
+		{`a.b`, "a\nb", false},
+		{`a.b`, "a\rb", false},
+		{`a.b`, "a\u2028b", false},
+		{`a.b`, "a\u2029b", false},
+		{`a.b`, "a\u00a0b", true},
+		{`a[.]b`, "a.b", true},
+		{`a[.]b`, "a-b", false},
+		{`a\.b`, "a.b", true},
+		{`a\.b`, "a-b", false},
+	} {
+		goPattern, jsOnly, err := translateJSPattern(test.pattern)
+		if err != nil || jsOnly != "" {
+			t.Errorf("translateJSPattern(%q) = %q, %q, %v", test.pattern, goPattern, jsOnly, err)
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + goPattern)
+		if err != nil {
+			t.Errorf("translateJSPattern(%q) = %q, which does not compile: %v", test.pattern, goPattern, err)
+			continue
+		}
+		if got := re.MatchString(test.input); got != test.match {
+			t.Errorf("%q (translated to %q) matching %q = %v, JavaScript gives %v", test.pattern, goPattern, test.input, got, test.match)
+		}
+	}
+}
+
+func TestTranslateJSPatternReportsNegatedWhitespaceInClass(t *testing.T) {
+	_, jsOnly, err := translateJSPattern(`[\S-]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsOnly == "" {
+		t.Error(`[\S-] is previewed although RE2 cannot translate \S inside a class`)
+	}
+}
+
+func TestReplaceAllJSExpandsLikeJavaScript(t *testing.T) {
+	// Expected results are those of text.replaceAll(new RegExp(pattern, "gi"), replaceWith) in Node.js
+	for _, test := range []struct {
+		text        string
+		pattern     string
+		replaceWith string
+		want        string
+	}{
+		{"a", `(a)`, "$10", "a0"},
+		{"a", `(a)`, "$01", "a"},
+		{"a", `(a)`, "$05", "$05"},
+		{"a", `(a)`, "$0", "$0"},
+		{"a", `(a)`, "$00", "$00"},
+		{"a", `(a)`, "$2", "$2"},
+		{"a", `a`, "$1", "$1"},
+		{"abcdefghijk", `(a)(b)(c)(d)(e)(f)(g)(h)(i)(j)(k)`, "$11", "k"},
+		{"a", `(a)`, "$1$$x", "a$x"},
+		{"xay", `(?<n>a)`, "[$<n>|$<m>|$`|$']", "x[a||x|y]y"},
+		{"xay", `(a)`, "[$<n>]", "x[$<n>]y"},
+		{"ab", `(a)|(b)`, "[$2]", "[][b]"},
+		{"Aa", `a`, "<$&>", "<A><a>"},
+	} {
+		re := regexp.MustCompile("(?i)" + test.pattern)
+		if got := replaceAllJS(re, test.text, test.replaceWith); got != test.want {
+			t.Errorf("%q replacing /%s/ with %q = %q, JavaScript gives %q", test.text, test.pattern, test.replaceWith, got, test.want)
+		}
+	}
+}
+
+func TestUnknownGroupReferences(t *testing.T) {
+	for _, test := range []struct {
+		pattern     string
+		replaceWith string
+		want        []string
+	}{
+		{`(a)`, "$1 $& $$ $` $'", []string{}},
+		{`(a)`, "$10", []string{}},
+		{`(a)`, "$2 $0 $05", []string{"$2", "$0", "$05"}},
+		{`a`, "$1", []string{"$1"}},
+		{`(?<n>a)`, "$<n> $<m>", []string{"$<m>"}},
+	} {
+		got := unknownGroupReferences(test.replaceWith, regexp.MustCompile(test.pattern))
+		if !slices.Equal(got, test.want) {
+			t.Errorf("unknownGroupReferences(%q, /%s/) = %q, want %q", test.replaceWith, test.pattern, got, test.want)
+		}
+	}
+}
+
+func TestCheckSSMLFragment(t *testing.T) {
+	for _, test := range []struct {
+		fragment string
+		valid    bool
+	}{
+		{"plain text", true},
+		{`<phoneme alphabet="ipa" ph="eɪˈsæp">ASAPP</phoneme>`, true},
+		{`<sub alias="Amazon Web Services">AWS</sub>`, true},
+		{`<break time="1s"/>`, true},
+		{`<amazon:effect name="whispered">hi</amazon:effect>`, true},
+		{"", true},
+		{`<sub alias="a">AWS`, false},
+		{`<sub alias="a"><phoneme ph="x">AWS</sub></phoneme>`, false},
+		{`<sub alias="<x>">AWS</sub>`, false},
+		{"Q&A", false},
+		{`<speak>nested</speak>`, false},
+		{`<b>bold</b>`, false},
+	} {
+		if err := checkSSMLFragment(test.fragment); (err == nil) != test.valid {
+			t.Errorf("checkSSMLFragment(%q) = %v, want valid %v", test.fragment, err, test.valid)
+		}
+	}
+}
+
+func TestLiteralMatches(t *testing.T) {
+	for _, test := range []struct {
+		searchFor string
+		want      []string
+	}{
+		{`ASAPP`, []string{"ASAPP"}},
+		{`\bASAPP\b`, []string{"ASAPP"}},
+		{`^US\$$`, []string{"US$"}},
+		{`(?<lexeme0>\bAWS Lambda\b)|(?<lexeme1>\bUS\$)|(a\.b)`, []string{"AWS Lambda", "US$", "a.b"}},
+		{`colou?r|grey`, []string{"grey"}},
+		{`\d+`, []string{}},
+		{`(a)(b)`, []string{}},
+		{`[|]`, []string{}},
+	} {
+		if got := literalMatches(test.searchFor); !slices.Equal(got, test.want) {
+			t.Errorf("literalMatches(%q) = %q, want %q", test.searchFor, got, test.want)
+		}
+	}
+}
+
+func TestCheckSSMLConversionsRunsTheChainOnMatchedText(t *testing.T) {
+	// The second rule rewrites Inc inside the alias the first one adds
+	_, _, err := checkSSMLConversions([]config.SSMLConversion{
+		{SearchFor: `\bASAPP\b`, ReplaceWith: `<sub alias="ASAPP Inc">$&</sub>`},
+		{SearchFor: `\bInc\b`, ReplaceWith: `<sub alias="Incorporated">$&</sub>`},
+	}, 0)
+	if err == nil || !strings.Contains(err.Error(), "ssmlConversions[0]") {
+		t.Errorf("error = %v, want one about ssmlConversions[0]", err)
+	}
+
+	// Lexemes are applied in one pass and cannot rewrite each other
+	_, _, err = checkSSMLConversions(lexiconSSMLConversions(testLexicons, "en-US"), 1)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -6,15 +6,19 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/handoff"
 
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsconnect"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awselasticache"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskms"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslambdanodejs"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
@@ -22,20 +26,19 @@ import (
 	"github.com/aws/aws-cdk-go/awscdk/v2/awss3deployment"
 	"github.com/aws/aws-cdk-go/awscdk/v2/customresources"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/connect"
 	"github.com/aws/aws-sdk-go-v2/service/connect/types"
 	"github.com/aws/jsii-runtime-go"
 
-	"github.com/iancoleman/orderedmap"
-
 	"github.com/aws/constructs-go/constructs/v10"
 )
 
 type AmazonConnectDemoCdkStackProps struct {
 	awscdk.StackProps
 	EnvName *string
+	// ModuleTransforms are applied to every Contact Flow Module after the built-in transforms, in order
+	ModuleTransforms []ModuleTransform
 }
 
 const (
@@ -45,11 +48,14 @@ const (
 	engageLambdaDir                           = "staging/lambdas/engage"
 	engageLambdaIndexPath                     = engageLambdaDir + "/index.mjs"
 	engageLambdaAttributeToInputVariablesPath = engageLambdaDir + "/attributesToInputVariables.mjs"
+	engageLambdaInputVariablesPath            = engageLambdaDir + "/inputVariables.mjs"
+	engageLambdaModulesPath                   = engageLambdaDir + "/modules.mjs"
 	engageLambdaLockPath                      = engageLambdaDir + "/package-lock.json"
 	pullActionLambdaDir                       = "staging/lambdas/pullaction"
 	pullActionLambdaIndexPath                 = pullActionLambdaDir + "/index.mjs"
 	pullActionLambdaLockPath                  = pullActionLambdaDir + "/package-lock.json"
 	pullActionSSMLConversionsPath             = pullActionLambdaDir + "/ssmlConversions.mjs"
+	pullActionLambdaModulesPath               = pullActionLambdaDir + "/modules.mjs"
 	pushActionLambdaDir                       = "staging/lambdas/pushaction"
 	pushActionLambdaIndexPath                 = pushActionLambdaDir + "/index.mjs"
 	pushActionLambdaLockPath                  = pushActionLambdaDir + "/package-lock.json"
@@ -57,7 +63,13 @@ const (
 	// Template paths
 	contactFlowModulePath = "../../flow-modules/template/ASAPPGenerativeAgent.json"
 
+	// Directory the transformed Contact Flow Modules are written to, for review with the flowdiagram command
+	flowModulesStagingDir = "staging/flow-modules"
+
 	lambdaFunctionAlias = "prod"
+
+	// Version of the quickstart, applied as a tag to all resources
+	Version = "2.0.1"
 )
 
 func NewQuickStartGenerativeAgentStack(scope constructs.Construct, id string, props *AmazonConnectDemoCdkStackProps, cfg *config.Config) awscdk.Stack {
@@ -67,10 +79,21 @@ func NewQuickStartGenerativeAgentStack(scope constructs.Construct, id string, pr
 	}
 	stack := awscdk.NewStack(scope, &id, &sprops)
 
+	// Tag every taggable resource created by the stack so it can be attributed to this deployment
+	stackTags := awscdk.Tags_Of(stack)
+	if props != nil && props.EnvName != nil {
+		stackTags.Add(jsii.String("envName"), props.EnvName, nil)
+	}
+	stackTags.Add(jsii.String("objectPrefix"), jsii.String(cfg.ObjectPrefix), nil)
+	stackTags.Add(jsii.String("quickstart-version"), jsii.String(Version), nil)
+	for key, value := range cfg.Tags {
+		stackTags.Add(jsii.String(key), jsii.String(value), nil)
+	}
+
 	awslogs.NewLogGroup(stack, generateObjectName(cfg, "stack-log-group"), &awslogs.LogGroupProps{
 		LogGroupName:  generateObjectName(cfg, "stack-log-group"),
 		Retention:     awslogs.RetentionDays_THREE_DAYS,
-		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
+		RemovalPolicy: removalPolicy(cfg, cfg.RemovalPolicies.LogGroups, awscdk.RemovalPolicy_RETAIN),
 	})
 
 	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(cfg.Region))
@@ -178,18 +201,42 @@ func NewQuickStartGenerativeAgentStack(scope constructs.Construct, id string, pr
 					"ResourceType":  "MEDIA_STREAMS",
 					"AssociationId": customresources.NewPhysicalResourceIdReference()},
 			},
-			Role: customResourceRole,
+			Role:          customResourceRole,
+			RemovalPolicy: removalPolicy(cfg, cfg.RemovalPolicies.KinesisStorageConfig, awscdk.RemovalPolicy_RETAIN),
 		})
 		kinesisPrefixResource.Node().AddDependency(customResourceRole, customResourcesPolicy)
 	}
 
 	// -- Setup the Prompts --
 	// Create an S3 Bucket to store the audio files
-	s3Bucket := awss3.NewBucket(stack, generateObjectName(cfg, "bucket"), &awss3.BucketProps{
+	s3BucketRemovalPolicy := removalPolicy(cfg, cfg.RemovalPolicies.PromptBucket, awscdk.RemovalPolicy_RETAIN)
+	s3BucketProps := &awss3.BucketProps{
 		Versioned:         jsii.Bool(false),
-		RemovalPolicy:     awscdk.RemovalPolicy_DESTROY,
-		AutoDeleteObjects: jsii.Bool(true),
-	})
+		RemovalPolicy:     s3BucketRemovalPolicy,
+		AutoDeleteObjects: jsii.Bool(s3BucketRemovalPolicy == awscdk.RemovalPolicy_DESTROY),
+	}
+	var s3BucketKey awskms.IKey
+	if !cfg.PromptBucket.DisableHardening {
+		// Block all public access, deny non-TLS requests and encrypt objects with SSE-KMS
+		s3BucketProps.BlockPublicAccess = awss3.BlockPublicAccess_BLOCK_ALL()
+		s3BucketProps.EnforceSSL = jsii.Bool(true)
+		s3BucketProps.BucketKeyEnabled = jsii.Bool(true)
+		if cfg.PromptBucket.KmsKeyArn != "" {
+			s3BucketKey = awskms.Key_FromKeyArn(stack, generateObjectName(cfg, "bucket-key"), jsii.String(cfg.PromptBucket.KmsKeyArn))
+			s3BucketProps.Encryption = awss3.BucketEncryption_KMS
+			s3BucketProps.EncryptionKey = s3BucketKey
+		} else {
+			s3BucketProps.Encryption = awss3.BucketEncryption_KMS_MANAGED
+		}
+		if cfg.PromptBucket.AccessLogsBucketName != "" {
+			s3BucketProps.ServerAccessLogsBucket = awss3.Bucket_FromBucketName(stack, generateObjectName(cfg, "bucket-access-logs"), jsii.String(cfg.PromptBucket.AccessLogsBucketName))
+			s3BucketProps.ServerAccessLogsPrefix = jsii.String(cfg.PromptBucket.AccessLogsPrefix)
+			// The imported bucket cannot be given the log delivery policy, S3 drops the logs when it is missing
+			fmt.Printf("Warning: promptBucket.accessLogsBucketName: the policy of bucket %s is not managed by the stack, server access logs are only delivered if it already has this statement:\n%s\n",
+				cfg.PromptBucket.AccessLogsBucketName, accessLogsPolicyStatement(cfg))
+		}
+	}
+	s3Bucket := awss3.NewBucket(stack, generateObjectName(cfg, "bucket"), s3BucketProps)
 
 	// Grant the custom Role read access to the S3 Bucket
 	customInstanceRole := awsiam.NewRole(stack, generateObjectName(cfg, "custom-instance-role"), &awsiam.RoleProps{
@@ -197,6 +244,11 @@ func NewQuickStartGenerativeAgentStack(scope constructs.Construct, id string, pr
 	})
 	s3Bucket.GrantRead(customInstanceRole, "*")
 
+	// Prompts are created by the custom resource Role, which reads the audio files and needs to decrypt them with the customer managed key
+	if s3BucketKey != nil {
+		s3BucketKey.GrantDecrypt(customResourceRole)
+	}
+
 	// Upload the audio files to the S3 Bucket
 	s3BucketDeployment := awss3deployment.NewBucketDeployment(stack, generateObjectName(cfg, "bucket-deployment"), &awss3deployment.BucketDeploymentProps{
 		Sources: &[]awss3deployment.ISource{
@@ -392,6 +444,7 @@ func NewQuickStartGenerativeAgentStack(scope constructs.Construct, id string, pr
 		TransitEncryptionEnabled:    jsii.Bool(false),
 		MultiAzEnabled:              jsii.Bool(true),
 	})
+	valkeyReplicationGroup.ApplyRemovalPolicy(removalPolicy(cfg, cfg.RemovalPolicies.Valkey, awscdk.RemovalPolicy_SNAPSHOT), nil)
 
 	attributeToVarsFile, err := os.Create(engageLambdaAttributeToInputVariablesPath)
 	if err != nil {
@@ -406,6 +459,36 @@ func NewQuickStartGenerativeAgentStack(scope constructs.Construct, id string, pr
 
 	}
 
+	inputVariablesFile, err := os.Create(engageLambdaInputVariablesPath)
+	if err != nil {
+		log.Fatalf("Failed to create inputVariablesFile: %v", err)
+		return nil
+	}
+	defer inputVariablesFile.Close()
+	err = writeInputVariablesFile(inputVariablesFile, cfg.InputVariables)
+	if err != nil {
+		log.Fatalf("Failed to write to inputVariablesFile: %v", err)
+		return nil
+	}
+
+	// Named Contact Flow Modules pass their name to the engage and pullaction functions, which pick the module configuration by name.
+	// Lexemes of the lexicons in the language of a module are applied before its SSML conversions.
+	flowModules := cfg.FlowModules()
+	moduleConfigs := moduleRuntimeConfigs(cfg, flowModules)
+	for _, modulesPath := range []string{engageLambdaModulesPath, pullActionLambdaModulesPath} {
+		modulesFile, err := os.Create(modulesPath)
+		if err != nil {
+			log.Fatalf("Failed to create modulesFile: %v", err)
+			return nil
+		}
+		defer modulesFile.Close()
+		err = writeModulesFile(modulesFile, moduleConfigs)
+		if err != nil {
+			log.Fatalf("Failed to write to modulesFile: %v", err)
+			return nil
+		}
+	}
+
 	/// -- Create the Lambda functions and associate them to the Connect Instance --
 	// Engage: this function only talks to Internet endpoints and is not attached to a VPC.
 	engageLambdaFunction := awslambdanodejs.NewNodejsFunction(stack, generateObjectName(cfg, "lambda-genagent-engage"), &awslambdanodejs.NodejsFunctionProps{
@@ -426,9 +509,13 @@ func NewQuickStartGenerativeAgentStack(scope constructs.Construct, id string, pr
 			ForceDockerBundling: jsii.Bool(true),
 		},
 		Environment: &map[string]*string{
-			"ASAPP_API_HOST":   jsii.String(cfg.Asapp.ApiHost),
-			"ASAPP_API_ID":     jsii.String(cfg.Asapp.ApiId),
-			"ASAPP_API_SECRET": jsii.String(cfg.Asapp.ApiSecret),
+			"ASAPP_API_HOST":           jsii.String(cfg.Asapp.ApiHost),
+			"ASAPP_API_ID":             jsii.String(cfg.Asapp.ApiId),
+			"ASAPP_API_SECRET":         jsii.String(cfg.Asapp.ApiSecret),
+			"ASAPP_LANGUAGE":           jsii.String(valueOrDefault(cfg.Engage.Language, "en-US")),
+			"ASAPP_FALLBACK_LANGUAGE":  jsii.String(valueOrDefault(cfg.Engage.FallbackLanguage, "en-US")),
+			"ASAPP_NAMESPACE":          jsii.String(valueOrDefault(cfg.Engage.Namespace, "amazonconnect")),
+			"ASAPP_CUSTOMER_ID_SOURCE": jsii.String(valueOrDefault(cfg.Engage.CustomerIdSource, "ContactData.CustomerEndpoint.Address")),
 		},
 	})
 	engageLambdaFunction.AddPermission(jsii.String("AmazonConnectInvokePermission"), &awslambda.Permission{
@@ -479,13 +566,47 @@ func NewQuickStartGenerativeAgentStack(scope constructs.Construct, id string, pr
 
 	associateEngageLambdaWithConnect.Node().AddDependency(engageLambdaFunction, engageLambdaAlias, customResourceRole, customResourcesPolicy)
 
+	// The unnamed module and requests without a module name use the top level text to speech settings
+	lexiconConversions := lexiconSSMLConversions(cfg.Lexicons, lexiconLanguage(cfg, &cfg.TextToSpeech))
+	ssmlConversions := append(lexiconConversions, cfg.SSMLConversions...)
+	if len(cfg.Lexicons) != 0 {
+		uploadLexicons(stack, cfg, customResourceRole, customResourcesPolicy)
+		for _, warning := range unusedLexiconWarnings(cfg, flowModules) {
+			fmt.Printf("Warning: %s\n", warning)
+		}
+	}
+
+	// Check the SSML conversions the way the pullaction function applies them and preview them on the configured samples
+	ssmlRules, ssmlWarnings, err := checkSSMLConversions(ssmlConversions, len(lexiconConversions))
+	if err != nil {
+		log.Fatalf("Invalid SSML conversions:\n%v", err)
+	}
+	for _, warning := range ssmlWarnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+	previewSSMLConversions("SSML conversions", ssmlRules, cfg.SSMLPreviewSamples)
+	for _, module := range flowModules {
+		moduleConversions := moduleConfigs[module.Name].SSMLConversions
+		if module.Name == "" || reflect.DeepEqual(moduleConversions, ssmlConversions) {
+			continue
+		}
+		moduleRules, moduleWarnings, err := checkSSMLConversions(moduleConversions, len(moduleConversions)-len(module.SSMLConversions))
+		if err != nil {
+			log.Fatalf("Invalid SSML conversions of module %s:\n%v", module.Name, err)
+		}
+		for _, warning := range moduleWarnings {
+			fmt.Printf("Warning: module %s: %s\n", module.Name, warning)
+		}
+		previewSSMLConversions(fmt.Sprintf("SSML conversions of module %s", module.Name), moduleRules, cfg.SSMLPreviewSamples)
+	}
+
 	ssmlConversionsFile, err := os.Create(pullActionSSMLConversionsPath)
 	if err != nil {
 		log.Fatalf("Failed to create ssmlConversionsFile: %v", err)
 		return nil
 	}
 	defer ssmlConversionsFile.Close()
-	err = writeSSMLConversionsFile(ssmlConversionsFile, cfg.SSMLConversions)
+	err = writeSSMLConversionsFile(ssmlConversionsFile, ssmlConversions)
 	if err != nil {
 		log.Fatalf("Failed to write to ssmlConversionsFile: %v", err)
 		return nil
@@ -546,6 +667,36 @@ func NewQuickStartGenerativeAgentStack(scope constructs.Construct, id string, pr
 		Role: customResourceRole,
 	})
 
+	// Let the engage and pullaction functions read their configuration from AWS AppConfig at runtime
+	if cfg.AppConfig.Enabled {
+		runtimeConfig := newRuntimeConfig(stack, cfg, ssmlConversions, moduleConfigs)
+		runtimeConfig.readRuntimeConfig(cfg, engageLambdaFunction)
+		runtimeConfig.readRuntimeConfig(cfg, pullActionLambdaFunction)
+		// SSML conversions can be added at runtime, so responses are always spoken as SSML
+		pullActionLambdaFunction.AddEnvironment(jsii.String("ASAPP_SSML_ALWAYS"), jsii.String("true"), nil)
+
+		// The AppConfig Lambda extension of pullaction reaches AppConfig from the isolated subnets through an interface endpoint
+		if cfg.AppConfig.SkipVpcEndpoint {
+			pullActionLambdaSecurityGroup.AddEgressRule(
+				awsec2.Peer_AnyIpv4(),
+				awsec2.Port_Tcp(aws.Float64(443)),
+				jsii.String("Allow outbound HTTPS traffic to AWS AppConfig"),
+				jsii.Bool(false),
+			)
+		} else {
+			appConfigEndpoint := awsec2.NewInterfaceVpcEndpoint(stack, generateObjectName(cfg, "appconfigdata-endpoint"), &awsec2.InterfaceVpcEndpointProps{
+				Vpc:     vpc,
+				Service: awsec2.InterfaceVpcEndpointAwsService_APPCONFIGDATA(),
+				Subnets: &awsec2.SubnetSelection{
+					Subnets: &vpcPrivateIsolatedSubnets,
+				},
+				PrivateDnsEnabled: jsii.Bool(true),
+				Open:              jsii.Bool(false),
+			})
+			appConfigEndpoint.Connections().AllowFrom(pullActionLambdaSecurityGroup, awsec2.Port_Tcp(aws.Float64(443)), jsii.String("Allow inbound HTTPS traffic from the pullaction function"))
+		}
+	}
+
 	pullActionLambdaVersion := pullActionLambdaFunction.CurrentVersion()
 	pullActionLambdaAliasProps := &awslambda.AliasProps{
 		AliasName:   jsii.String(lambdaFunctionAlias),
@@ -612,77 +763,117 @@ func NewQuickStartGenerativeAgentStack(scope constructs.Construct, id string, pr
 		return nil
 	}
 
-	// Unmarshal the JSON data into a map
-	var contactFlowModuleContentMap orderedmap.OrderedMap
-	if err := json.Unmarshal(contactFlowModuleContent, &contactFlowModuleContentMap); err != nil {
-		fmt.Printf("Failed to unmarshal JSON: %v\n", err)
-		return nil
-	}
-
-	// Setup a map with the newly created Prompts ARNs to be replaced in the Contact Flow Module
-	parsedInstanceIdArn, err := arn.Parse(cfg.ConnectInstanceArn)
+	// Setup maps with the newly created Prompts ARNs, Lambda Function ARNs and names to be replaced in the Contact Flow Module
+	instanceId, err := connectInstanceId(cfg.ConnectInstanceArn)
 	if err != nil {
 		log.Fatalf("Failed to parse ConnectInstanceArn: %v", err)
 	}
-	resourceArnSection := strings.Split(parsedInstanceIdArn.Resource, "/")
-
-	var connectInstanceId string
-	if len(resourceArnSection) == 2 && resourceArnSection[0] == "instance" { // Check if the resource section has "instance" and get the instance ID
-		connectInstanceId = resourceArnSection[1]
-	}
-	promptArnsMap := map[string]string{
-		"Wait1sPrompt":     fmt.Sprintf("arn:aws:connect:%s:%s:instance/%s/prompt/%s", cfg.Region, cfg.AccountId, connectInstanceId, *silence1secondPromptId),
-		"Wait400msPrompt":  fmt.Sprintf("arn:aws:connect:%s:%s:instance/%s/prompt/%s", cfg.Region, cfg.AccountId, connectInstanceId, *silence400msPromptId),
-		"PlayBeepBopShort": fmt.Sprintf("arn:aws:connect:%s:%s:instance/%s/prompt/%s", cfg.Region, cfg.AccountId, connectInstanceId, *beepBopShortPromptId),
+	resources := moduleResources{partition: cfg.Partition()}
+	resources.promptArns = map[string]string{
+		promptWait1s:    connectPromptArn(cfg.Partition(), cfg.Region, cfg.AccountId, instanceId, *silence1secondPromptId),
+		promptWait400ms: connectPromptArn(cfg.Partition(), cfg.Region, cfg.AccountId, instanceId, *silence400msPromptId),
+		promptBeepBop:   connectPromptArn(cfg.Partition(), cfg.Region, cfg.AccountId, instanceId, *beepBopShortPromptId),
 	}
 
-	// Setup a map with the newly created Lambda Function ARNs to be replaced in the Contact Flow Module
-	lambdaFunctionsArnMap := map[string]string{
-		"Engage":     *engageLambdaAlias.FunctionArn(),
-		"PullAction": *pullActionLambdaAlias.FunctionArn(),
+	resources.lambdaArns = map[string]string{
+		lambdaEngage:     *engageLambdaAlias.FunctionArn(),
+		lambdaPullAction: *pullActionLambdaAlias.FunctionArn(),
 	}
 
-	displayNameMap := map[string]string{
-		"generativeagent-quickstart-lambda-genagent-engage": *engageLambdaAlias.FunctionName(),
-		"generativeagent-quickstart-lambda-pullaction":      *pullActionLambdaAlias.FunctionName(),
+	resources.lambdaNames = map[string]string{
+		lambdaEngage:     *engageLambdaAlias.FunctionName(),
+		lambdaPullAction: *pullActionLambdaAlias.FunctionName(),
 	}
 
-	// Update the referenced resources (Prompts and Lambda functions), then Marshal the content into the same variable.
-	UpdateResourcesARN(&contactFlowModuleContentMap, cfg.Region, cfg.AccountId, cfg.ConnectInstanceArn, promptArnsMap, lambdaFunctionsArnMap, displayNameMap)
+	// Create one Contact Flow Module per configured module from the same template, the first one is invoked by the sample contact flow
+	var connectModules []awsconnect.CfnContactFlowModule
+	for _, module := range flowModules {
+		// Apply the built-in transforms, then the ones of the stack props
+		var transforms []ModuleTransform
+		if props != nil {
+			transforms = props.ModuleTransforms
+		}
+		contactFlowModuleContentMap, reports, err := buildFlowModule(contactFlowModuleContent, cfg, module, resources,
+			len(moduleSSMLConversions(cfg, module)) != 0 || cfg.AppConfig.Enabled, transforms)
+		if err != nil {
+			log.Fatalf("Failed to build Contact Flow Module %s: %v", *moduleObjectName(cfg, module), err)
+		}
+		printTransformReports(*moduleObjectName(cfg, module), reports)
 
-	// Update Output Variables
-	UpdateExtractOutputVariables(&contactFlowModuleContentMap, cfg.OutputVariablesToAttributesMap)
+		moduleContent, err := json.MarshalIndent(contactFlowModuleContentMap, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to marshal updated JSON: %v\n", err)
+		}
+		if err := os.MkdirAll(flowModulesStagingDir, 0755); err != nil {
+			log.Fatalf("Failed to create %s directory: %v", flowModulesStagingDir, err)
+		}
+		if err := os.WriteFile(filepath.Join(flowModulesStagingDir, *moduleObjectName(cfg, module)+".json"), moduleContent, 0644); err != nil {
+			log.Fatalf("Failed to write Contact Flow Module %s: %v", *moduleObjectName(cfg, module), err)
+		}
 
-	// Update SpeakResponse in module if SSML conversions are provided
-	if len(cfg.SSMLConversions) != 0 {
-		UpdateSpeakResponseToSSML(&contactFlowModuleContentMap)
+		// Create the Contact Flow Module
+		connectModule := awsconnect.NewCfnContactFlowModule(stack, moduleObjectName(cfg, module), &awsconnect.CfnContactFlowModuleProps{
+			InstanceArn: jsii.String(cfg.ConnectInstanceArn),
+			Name:        moduleObjectName(cfg, module),
+			Content:     jsii.String(string(moduleContent)),
+		})
 
+		// Wait for the Prompts to be ready before proceeding to create the Contact Flow Module
+		connectModule.Node().AddDependency(createBeepbopShortPrompt, createSilence1secondPrompt, createSilence400msPrompt)
+		connectModules = append(connectModules, connectModule)
 	}
-
-	contactFlowModuleContent, err = json.MarshalIndent(contactFlowModuleContentMap, "", "  ")
-	if err != nil {
-		log.Fatalf("Failed to marshal updated JSON: %v\n", err)
+	connectModule := connectModules[0]
+
+	// Create the sample inbound contact flow invoking the module
+	var sampleContactFlow awsconnect.CfnContactFlow
+	if cfg.SampleContactFlow.Enabled {
+		sampleContactFlowContent, err := buildSampleContactFlow(*resourceIdFromArn(connectModule.AttrContactFlowModuleArn()), cfg.SampleContactFlow, flowModules[0].TransferToAgentQueues.Enabled())
+		if err != nil {
+			log.Fatalf("Failed to build sample contact flow: %v\n", err)
+		}
+		sampleContactFlow = awsconnect.NewCfnContactFlow(stack, generateObjectName(cfg, "sample-contact-flow"), &awsconnect.CfnContactFlowProps{
+			InstanceArn: jsii.String(cfg.ConnectInstanceArn),
+			Name:        generateObjectName(cfg, "sample-contact-flow"),
+			Type:        jsii.String("CONTACT_FLOW"),
+			Description: jsii.String("Sample inbound flow invoking the ASAPP GenerativeAgent module and routing on ASAPP_Disposition"),
+			Content:     jsii.String(string(sampleContactFlowContent)),
+		})
 	}
 
-	// Create the Contact Flow Module
-	connectModule := awsconnect.NewCfnContactFlowModule(stack, generateObjectName(cfg, "contact-flow-module"), &awsconnect.CfnContactFlowModuleProps{
-		InstanceArn: jsii.String(cfg.ConnectInstanceArn),
-		Name:        generateObjectName(cfg, "contact-flow-module"),
-		Content:     jsii.String(string(contactFlowModuleContent)),
-	})
-
-	// Wait for the Prompts to be ready before proceeding to create the Contact Flow Module
-	connectModule.Node().AddDependency(createBeepbopShortPrompt, createSilence1secondPrompt, createSilence400msPrompt)
+	// Associate phone numbers with the configured contact flow, or with the sample contact flow
+	var claimedPhoneNumber awsconnect.CfnPhoneNumber
+	if len(cfg.PhoneNumbers.Arns) > 0 || cfg.PhoneNumbers.Claim.CountryCode != "" {
+		phoneNumbersContactFlowArn := jsii.String(cfg.PhoneNumbers.ContactFlowArn)
+		if cfg.PhoneNumbers.ContactFlowArn == "" {
+			phoneNumbersContactFlowArn = sampleContactFlow.AttrContactFlowArn()
+		}
+		claimedPhoneNumber = associatePhoneNumbers(stack, cfg, phoneNumbersContactFlowArn, customResourceRole, customResourcesPolicy)
+	}
 
 	// -- Create the Role: generativeagent-quickstart-access-role --
-	asappGenagentAccessRole := awsiam.NewRole(stack, generateObjectName(cfg, "access-role"), &awsiam.RoleProps{
-		RoleName: generateObjectName(cfg, "access-role"),
-		AssumedBy: awsiam.NewCompositePrincipal(
-			awsiam.NewArnPrincipal(jsii.String(cfg.Asapp.AssumingRoleArn)), // TrustASAPPRole
-		)})
+	// ASAPP must present the external ID when assuming the role, which protects against the confused deputy problem
+	externalId := cfg.Asapp.ExternalId
+	trustConditions := map[string]interface{}{
+		"StringEquals": map[string]interface{}{
+			"sts:ExternalId": externalId,
+		},
+	}
+	if len(cfg.Asapp.AllowedSourceIps) > 0 {
+		trustConditions["IpAddress"] = map[string]interface{}{
+			"aws:SourceIp": cfg.Asapp.AllowedSourceIps,
+		}
+	}
+	asappGenagentAccessRoleProps := &awsiam.RoleProps{
+		RoleName:  generateObjectName(cfg, "access-role"),
+		AssumedBy: awsiam.NewArnPrincipal(jsii.String(cfg.Asapp.AssumingRoleArn)).WithConditions(&trustConditions), // TrustASAPPRole
+	}
+	if cfg.Asapp.MaxSessionDurationSeconds > 0 {
+		asappGenagentAccessRoleProps.MaxSessionDuration = awscdk.Duration_Seconds(jsii.Number(cfg.Asapp.MaxSessionDurationSeconds))
+	}
+	asappGenagentAccessRole := awsiam.NewRole(stack, generateObjectName(cfg, "access-role"), asappGenagentAccessRoleProps)
 
 	var sbAsappKinesisAccessPolicy strings.Builder
-	sbAsappKinesisAccessPolicy.WriteString("arn:aws:kinesisvideo:*:")
+	sbAsappKinesisAccessPolicy.WriteString("arn:" + cfg.Partition() + ":kinesisvideo:*:")
 	sbAsappKinesisAccessPolicy.WriteString(cfg.AccountId)
 	sbAsappKinesisAccessPolicy.WriteString(":stream/")
 	sbAsappKinesisAccessPolicy.WriteString(kinesisVideoStreamConfigPrefix)
@@ -749,15 +940,40 @@ func NewQuickStartGenerativeAgentStack(scope constructs.Construct, id string, pr
 	})
 	asappGenagentAccessRole.AttachInlinePolicy(asappInvokePushActionLambdaPolicy)
 
-	// Output the ARN of the Role
-	awscdk.NewCfnOutput(stack, jsii.String("iamrolearn"), &awscdk.CfnOutputProps{
-		Value: asappGenagentAccessRole.RoleArn(),
-	})
-
-	// Output the ARN of the pushaction lambda
-	awscdk.NewCfnOutput(stack, jsii.String("pushactionlambdaarn"), &awscdk.CfnOutputProps{
-		Value: pushActionLambdaAlias.FunctionArn(),
-	})
+	// Output the values ASAPP needs and the identifiers of the deployed resources, see cmd/handoff
+	deploymentOutputs := []deploymentOutput{
+		{handoff.OutputAccessRoleArn, "ARN of the Role assumed by ASAPP", asappGenagentAccessRole.RoleArn()},
+		{handoff.OutputExternalId, "External ID ASAPP has to use when assuming the Role", jsii.String(externalId)},
+		{handoff.OutputPushActionLambdaArn, "ARN of the PushAction Lambda alias invoked by ASAPP", pushActionLambdaAlias.FunctionArn()},
+		{handoff.OutputKinesisVideoStreamPrefix, "Kinesis Video Stream prefix of the Amazon Connect instance", jsii.String(kinesisVideoStreamConfigPrefix)},
+		{handoff.OutputConnectInstanceArn, "ARN of the Amazon Connect instance", jsii.String(cfg.ConnectInstanceArn)},
+		{handoff.OutputFlowModuleArn, "ARN of the Contact Flow Module", connectModule.AttrContactFlowModuleArn()},
+		{handoff.OutputFlowModuleId, "ID of the Contact Flow Module", resourceIdFromArn(connectModule.AttrContactFlowModuleArn())},
+		{handoff.OutputEngageLambdaArn, "ARN of the Engage Lambda alias invoked by Amazon Connect", engageLambdaAlias.FunctionArn()},
+		{handoff.OutputPullActionLambdaArn, "ARN of the PullAction Lambda alias invoked by Amazon Connect", pullActionLambdaAlias.FunctionArn()},
+		{handoff.OutputValkeyEndpoint, "Primary endpoint of the Valkey replication group", awscdk.Fn_Join(jsii.String(":"), &[]*string{
+			valkeyReplicationGroup.AttrPrimaryEndPointAddress(),
+			valkeyReplicationGroup.AttrPrimaryEndPointPort(),
+		})},
+		{handoff.OutputBeepBopPromptId, "ID of the asappBeepBop prompt", beepBopShortPromptId},
+		{handoff.OutputSilence1secondPromptId, "ID of the asappSilence1second prompt", silence1secondPromptId},
+		{handoff.OutputSilence400msPromptId, "ID of the asappSilence400ms prompt", silence400msPromptId},
+	}
+	for i, module := range flowModules {
+		if module.Name != "" {
+			deploymentOutputs = append(deploymentOutputs, deploymentOutput{moduleOutputKey(module), fmt.Sprintf("ARN of the %s Contact Flow Module", module.Name), connectModules[i].AttrContactFlowModuleArn()})
+		}
+	}
+	if sampleContactFlow != nil {
+		deploymentOutputs = append(deploymentOutputs, deploymentOutput{handoff.OutputSampleContactFlowArn, "ARN of the sample contact flow invoking the Contact Flow Module", sampleContactFlow.AttrContactFlowArn()})
+	}
+	if claimedPhoneNumber != nil {
+		deploymentOutputs = append(deploymentOutputs, deploymentOutput{handoff.OutputClaimedPhoneNumber, "Phone number claimed for the Amazon Connect instance", claimedPhoneNumber.AttrAddress()})
+	}
+	addDeploymentOutputs(stack, deploymentOutputs)
+	if cfg.SsmParameters.Enabled {
+		addDeploymentParameters(stack, cfg, deploymentOutputs)
+	}
 
 	return stack
 }
@@ -766,3 +982,51 @@ func generateObjectName(cfg *config.Config, name string) *string {
 	val := fmt.Sprintf("%s%s", cfg.ObjectPrefix, name)
 	return &val
 }
+
+// valueOrDefault returns value, or defaultValue when value is empty
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// removalPolicy resolves the removal policy of a resource from its override in removalPolicies. Without an override
+// the resource gets retainPolicy when retainOnDelete is set, and is destroyed otherwise.
+func removalPolicy(cfg *config.Config, override string, retainPolicy awscdk.RemovalPolicy) awscdk.RemovalPolicy {
+	switch override {
+	case config.RemovalPolicyDestroy:
+		return awscdk.RemovalPolicy_DESTROY
+	case config.RemovalPolicyRetain:
+		return awscdk.RemovalPolicy_RETAIN
+	case config.RemovalPolicySnapshot:
+		return awscdk.RemovalPolicy_SNAPSHOT
+	}
+	if cfg.RetainOnDelete {
+		return retainPolicy
+	}
+	return awscdk.RemovalPolicy_DESTROY
+}
+
+// accessLogsPolicyStatement returns the bucket policy statement letting S3 deliver the server access logs of the
+// prompt bucket to the configured bucket and prefix
+func accessLogsPolicyStatement(cfg *config.Config) string {
+	statement := struct {
+		Effect    string
+		Principal map[string]string
+		Action    string
+		Resource  string
+		Condition map[string]map[string]string
+	}{
+		Effect:    "Allow",
+		Principal: map[string]string{"Service": "logging.s3.amazonaws.com"},
+		Action:    "s3:PutObject",
+		Resource:  fmt.Sprintf("arn:%s:s3:::%s/%s*", cfg.Partition(), cfg.PromptBucket.AccessLogsBucketName, cfg.PromptBucket.AccessLogsPrefix),
+		Condition: map[string]map[string]string{"StringEquals": {"aws:SourceAccount": cfg.AccountId}},
+	}
+	policy, err := json.MarshalIndent(statement, "", "    ")
+	if err != nil {
+		log.Fatalf("Failed to marshal the access logs policy statement: %v", err)
+	}
+	return string(policy)
+}
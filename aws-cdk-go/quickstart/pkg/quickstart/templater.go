@@ -1,8 +1,11 @@
 package quickstart
 
 import (
+	"bytes"
 	_ "embed"
+	"encoding/json"
 	"io"
+	"strings"
 	"text/template"
 
 	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
@@ -11,43 +14,68 @@ import (
 //go:embed attributesToInputVariables.tmpl
 var attributesToInputVariablesTemplate string
 
+//go:embed inputVariables.tmpl
+var inputVariablesTemplate string
+
 //go:embed ssmlConversions.tmpl
 var ssmlConversionsTemplate string
 
-func writeAttributesToInputVariablesFile(outputFile io.Writer, attributesToInputVariables map[string]string) error {
+//go:embed modules.tmpl
+var modulesTemplate string
 
-	var fns = template.FuncMap{
-		"plus1": func(x int) int {
-			return x + 1
-		},
-	}
+// Data is never spliced into the generated modules as raw text: the templates only render it through the json
+// function, and JSON values are valid JavaScript expressions, so quotes, backslashes and line breaks in config
+// values are escaped.
+var templateFuncs = template.FuncMap{
+	"json": jsonValue,
+}
 
-	tmpl, err := template.New("templates").Funcs(fns).Parse(attributesToInputVariablesTemplate)
-	if err != nil {
-		return err
+// jsonValue encodes value as indented JSON. HTML characters are kept as is so SSML stays readable.
+func jsonValue(value any) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(value); err != nil {
+		return "", err
 	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
 
-	// Execute the template with the data
-	err = tmpl.Execute(outputFile, attributesToInputVariables)
-	if err != nil {
-		return err
+func writeAttributesToInputVariablesFile(outputFile io.Writer, attributesToInputVariables map[string]string) error {
+	if attributesToInputVariables == nil {
+		attributesToInputVariables = map[string]string{}
 	}
+	return executeTemplate(outputFile, attributesToInputVariablesTemplate, attributesToInputVariables)
+}
 
-	return nil
+func writeInputVariablesFile(outputFile io.Writer, inputVariables []config.InputVariable) error {
+	if inputVariables == nil {
+		inputVariables = []config.InputVariable{}
+	}
+	return executeTemplate(outputFile, inputVariablesTemplate, inputVariables)
 }
 
 func writeSSMLConversionsFile(outputFile io.Writer, ssmlConversions []config.SSMLConversion) error {
+	if ssmlConversions == nil {
+		ssmlConversions = []config.SSMLConversion{}
+	}
+	return executeTemplate(outputFile, ssmlConversionsTemplate, ssmlConversions)
+}
 
-	tmpl, err := template.New("templates").Parse(ssmlConversionsTemplate)
-	if err != nil {
-		return err
+func writeModulesFile(outputFile io.Writer, modules map[string]runtimeConfig) error {
+	if modules == nil {
+		modules = map[string]runtimeConfig{}
 	}
+	return executeTemplate(outputFile, modulesTemplate, modules)
+}
 
-	// Execute the template with the data
-	err = tmpl.Execute(outputFile, ssmlConversions)
+func executeTemplate(outputFile io.Writer, text string, data any) error {
+	tmpl, err := template.New("templates").Funcs(templateFuncs).Parse(text)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Execute the template with the data
+	return tmpl.Execute(outputFile, data)
 }
@@ -0,0 +1,125 @@
+package quickstart
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+)
+
+// awkwardStrings are config values that broke the generated modules before they were JSON encoded
+var awkwardStrings = []string{
+	`it's`,
+	`say "hello"`,
+	`C:\path\n`,
+	"first line\nsecond line\r\n",
+	`</script><script>alert(1)</script>`,
+	"line\u2028separator\u2029paragraph",
+	"crème brûlée, 日本語, 😀",
+	`<phoneme alphabet="ipa" ph="eɪˈsæp">ASAPP</phoneme>`,
+}
+
+// decodeGeneratedModule returns the value exported by a generated module
+func decodeGeneratedModule(t *testing.T, module string, value any) {
+	t.Helper()
+	start := strings.Index(module, "export default ")
+	if start < 0 {
+		t.Fatalf("no default export in:\n%s", module)
+	}
+	exported := strings.TrimSpace(module[start+len("export default "):])
+	exported, ok := strings.CutSuffix(exported, ";")
+	if !ok {
+		t.Fatalf("default export does not end with a semicolon:\n%s", module)
+	}
+	if err := json.Unmarshal([]byte(exported), value); err != nil {
+		t.Fatalf("default export is not JSON: %v\n%s", err, exported)
+	}
+}
+
+func TestWriteAttributesToInputVariablesFileRoundTrip(t *testing.T) {
+	attributes := map[string]string{}
+	for i, s := range awkwardStrings {
+		attributes[s] = awkwardStrings[len(awkwardStrings)-1-i]
+	}
+
+	for name, input := range map[string]map[string]string{
+		"nil":      nil,
+		"empty":    {},
+		"awkward":  attributes,
+		"one pair": {"tier": "customerTier"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeAttributesToInputVariablesFile(&buf, input); err != nil {
+				t.Fatal(err)
+			}
+			decoded := map[string]string{}
+			decodeGeneratedModule(t, buf.String(), &decoded)
+			want := input
+			if want == nil {
+				want = map[string]string{}
+			}
+			if !reflect.DeepEqual(decoded, want) {
+				t.Errorf("decoded %q, want %q", decoded, want)
+			}
+		})
+	}
+}
+
+func TestWriteSSMLConversionsFileRoundTrip(t *testing.T) {
+	conversions := []config.SSMLConversion{}
+	for i, s := range awkwardStrings {
+		conversions = append(conversions, config.SSMLConversion{
+			SearchFor:   s,
+			ReplaceWith: awkwardStrings[len(awkwardStrings)-1-i],
+		})
+	}
+
+	for name, input := range map[string][]config.SSMLConversion{
+		"nil":     nil,
+		"empty":   {},
+		"awkward": conversions,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeSSMLConversionsFile(&buf, input); err != nil {
+				t.Fatal(err)
+			}
+			decoded := []config.SSMLConversion{}
+			decodeGeneratedModule(t, buf.String(), &decoded)
+			want := input
+			if want == nil {
+				want = []config.SSMLConversion{}
+			}
+			if !reflect.DeepEqual(decoded, want) {
+				t.Errorf("decoded %q, want %q", decoded, want)
+			}
+		})
+	}
+}
+
+func TestGeneratedModulesKeepValuesOnOneStatement(t *testing.T) {
+	// Raw line and paragraph separators are line terminators in JavaScript, the encoded values must escape them
+	for name, write := range map[string]func(io.Writer) error{
+		"attributesToInputVariables": func(w io.Writer) error {
+			return writeAttributesToInputVariablesFile(w, map[string]string{"key": awkwardStrings[5]})
+		},
+		"ssmlConversions": func(w io.Writer) error {
+			return writeSSMLConversionsFile(w, []config.SSMLConversion{{SearchFor: awkwardStrings[5], ReplaceWith: "x"}})
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if strings.ContainsAny(buf.String(), "\u2028\u2029") {
+				t.Errorf("generated module holds a raw line or paragraph separator:\n%s", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package quickstart
+
+import (
+	"fmt"
+
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
+	"github.com/iancoleman/orderedmap"
+)
+
+// FlowModule is a Contact Flow Module being built from the flow module template
+type FlowModule struct {
+	// Name is the name of the module in the modules config, empty for the single module of a deployment without
+	// modules
+	Name string
+	// Content is the module in the Amazon Connect flow language, changed in place by the transforms
+	Content *orderedmap.OrderedMap
+}
+
+// ModuleTransform is a change applied to a Contact Flow Module before it is deployed. Apply returns a description
+// of each change it made, which is printed at synth time, and an error when the module cannot be changed.
+type ModuleTransform interface {
+	Name() string
+	Apply(module FlowModule) ([]string, error)
+}
+
+// NewModuleTransform returns a ModuleTransform named name that applies apply
+func NewModuleTransform(name string, apply func(module FlowModule) ([]string, error)) ModuleTransform {
+	return moduleTransformFunc{name: name, apply: apply}
+}
+
+type moduleTransformFunc struct {
+	name  string
+	apply func(module FlowModule) ([]string, error)
+}
+
+func (t moduleTransformFunc) Name() string {
+	return t.name
+}
+
+func (t moduleTransformFunc) Apply(module FlowModule) ([]string, error) {
+	return t.apply(module)
+}
+
+// TransformReport lists the changes a transform made to a module
+type TransformReport struct {
+	Transform string
+	Changes   []string
+}
+
+// ModulePipeline applies transforms to a Contact Flow Module in the order they were registered
+type ModulePipeline struct {
+	transforms []ModuleTransform
+}
+
+// Register appends transforms to the pipeline
+func (p *ModulePipeline) Register(transforms ...ModuleTransform) {
+	p.transforms = append(p.transforms, transforms...)
+}
+
+// Apply runs the transforms on module in order, stopping at the first one that fails
+func (p *ModulePipeline) Apply(module FlowModule) ([]TransformReport, error) {
+	reports := []TransformReport{}
+	for _, transform := range p.transforms {
+		changes, err := transform.Apply(module)
+		if err != nil {
+			return reports, fmt.Errorf("%s: %w", transform.Name(), err)
+		}
+		reports = append(reports, TransformReport{Transform: transform.Name(), Changes: changes})
+	}
+	return reports, nil
+}
+
+// moduleResources are the resources of the deployment the placeholders of the flow module template refer to
+type moduleResources struct {
+	partition string
+	// promptArns, lambdaArns and lambdaNames are keyed by the name of the prompt or function in the placeholders
+	promptArns  map[string]string
+	lambdaArns  map[string]string
+	lambdaNames map[string]string
+}
+
+// placeholderValues returns the values of the placeholders of the flow module template
+func (r moduleResources) placeholderValues() map[string]string {
+	values := map[string]string{placeholderPartition: r.partition}
+	for name, promptArn := range r.promptArns {
+		values[placeholderPrompt+name] = promptArn
+	}
+	for name, lambdaArn := range r.lambdaArns {
+		values[placeholderLambda+name] = lambdaArn
+	}
+	for name, lambdaName := range r.lambdaNames {
+		values[placeholderLambdaName+name] = lambdaName
+	}
+	return values
+}
+
+// builtinModuleTransforms returns the transforms the stack applies to module, ssml is set when the module speaks
+// responses as SSML
+func builtinModuleTransforms(cfg *config.Config, module config.ModuleConfig, resources moduleResources, ssml bool) []ModuleTransform {
+	// Move the ARNs written in the template to the deployment, then set the referenced resources (Prompts and Lambda
+	// functions) through the placeholders
+	transforms := []ModuleTransform{
+		NewModuleTransform("resource-arns", func(m FlowModule) ([]string, error) {
+			return UpdateResourcesARN(m.Content, cfg.Partition(), cfg.Region, cfg.AccountId, cfg.FlowModuleArns.RelocatedArns())
+		}),
+		NewModuleTransform("placeholders", func(m FlowModule) ([]string, error) {
+			return ResolvePlaceholders(m.Content, resources.placeholderValues())
+		}),
+	}
+
+	// Let the Lambda functions pick the configuration of named modules
+	if module.Name != "" {
+		transforms = append(transforms, NewModuleTransform("module-name", func(m FlowModule) ([]string, error) {
+			return UpdateModuleName(m.Content, module.Name)
+		}))
+	}
+
+	// Update Output Variables
+	transforms = append(transforms, NewModuleTransform("output-variables", func(m FlowModule) ([]string, error) {
+		return UpdateExtractOutputVariables(m.Content, module.OutputVariablesToAttributesMap)
+	}))
+
+	// Set the voice the module speaks with when configured
+	if module.TextToSpeech.Voice != "" {
+		transforms = append(transforms, NewModuleTransform("text-to-speech-voice", func(m FlowModule) ([]string, error) {
+			return UpdateTextToSpeechVoice(m.Content, module.TextToSpeech.Voice, module.TextToSpeech.Engine, module.TextToSpeech.Language)
+		}))
+	}
+
+	// Set the queue of transferToAgent dispositions when queue routing is configured
+	if module.TransferToAgentQueues.Enabled() {
+		queues := module.TransferToAgentQueues
+		transforms = append(transforms, NewModuleTransform("transfer-to-agent-queue", func(m FlowModule) ([]string, error) {
+			return UpdateTransferToAgentQueue(m.Content, queues.OutputVariable, queues.QueueArns, queues.DefaultQueueArn)
+		}))
+	}
+
+	// Update SpeakResponse in module if SSML conversions are provided, or can be added at runtime through AppConfig
+	if ssml {
+		transforms = append(transforms, NewModuleTransform("speak-ssml", func(m FlowModule) ([]string, error) {
+			return UpdateSpeakResponseToSSML(m.Content)
+		}))
+	}
+	return transforms
+}
+
+// printTransformReports prints the changes the transforms made to the module named name
+func printTransformReports(name string, reports []TransformReport) {
+	fmt.Printf("Contact Flow Module %s:\n", name)
+	for _, report := range reports {
+		if len(report.Changes) == 0 {
+			fmt.Printf("  %s: no changes\n", report.Transform)
+			continue
+		}
+		fmt.Printf("  %s:\n", report.Transform)
+		for _, change := range report.Changes {
+			fmt.Printf("    %s\n", change)
+		}
+	}
+}
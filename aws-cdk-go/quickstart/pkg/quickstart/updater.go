@@ -2,65 +2,78 @@ package quickstart
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
 
+	"github.com/asappinc/generativeagent-amazon-connect/pkg/config"
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/iancoleman/orderedmap"
 )
 
-func UpdateResourcesARN(data *orderedmap.OrderedMap, region, accountId, connectInstanceArn string,
-	promptArnMap, lambdaFunctionsArnMap, displayNameMap map[string]string) {
+// The Update functions change the Contact Flow Module in place and return a description of each change they made.
+// They return an error when the module does not have the shape of the Amazon Connect flow language.
+
+// UpdateResourcesARN moves the ARNs written in the module whose service or resource type is listed in relocate, e.g.
+// lambda or lambda:function, to the partition, region and account of the deployment. Other ARNs, such as cross-account
+// resources or queues of an instance, are left as they are. It reports every ARN it found, relocated or not. The
+// resources of the deployment are set through the placeholders of the template, see ResolvePlaceholders.
+func UpdateResourcesARN(data *orderedmap.OrderedMap, partition, region, accountId string, relocate []string) ([]string, error) {
+	changes := []string{}
 	for _, key := range data.Keys() {
 		value, _ := data.Get(key)
 		strValue, ok := value.(string)
 		if ok {
-			newPromptValue, newPromptKeyExists := promptArnMap[strValue]
-			if key == "Identifier" && newPromptKeyExists { // Updates the ARN of the Prompts whose Identifier is present in the map
-				parametersValue, _ := data.Get("Parameters")
-				parametersValueMap := parametersValue.(orderedmap.OrderedMap)
-				parametersValueMap.Set("PromptId", newPromptValue)
-				continue
-			}
-			newLambdaFunctionValue, newLambdaKeyExists := lambdaFunctionsArnMap[strValue]
-			if key == "Identifier" && newLambdaKeyExists { // Updates the ARN of the Lambda functions whose Identifier is present in the map
-				parametersValue, _ := data.Get("Parameters")
-				parametersValueMap := parametersValue.(orderedmap.OrderedMap)
-				parametersValueMap.Set("LambdaFunctionARN", newLambdaFunctionValue)
-				continue
-			}
-
-			/*
-			 *	If the key is "displayName", we check if the value is in the displayNameMap and update it if it exists.
-			 *	This is useful for updating the display names of Lambda functions in the Contact Flow Module to match the actual function names.
-			 */
-			if key == "displayName" {
-				if newDisplayName, exists := displayNameMap[strValue]; exists {
-					data.Set(key, newDisplayName)
-				}
-			}
-
-			if arn.IsARN(strValue) {
+			if arn.IsARN(strValue) && !strings.Contains(strValue, "{{") {
 				arnValue, err := arn.Parse(strValue)
 				if err != nil {
-					panic(err)
+					return nil, fmt.Errorf("%s: %q is not a valid ARN: %w", key, strValue, err)
+				}
+				resource := arnValue.Service
+				if resourceType := arnResourceType(arnValue); resourceType != "" {
+					resource += ":" + resourceType
+				}
+				if !slices.Contains(relocate, arnValue.Service) && !slices.Contains(relocate, resource) {
+					changes = append(changes, fmt.Sprintf("left %s as is, %s is not relocated", strValue, resource))
+					continue
 				}
+				arnValue.Partition = partition
 				arnValue.Region = region
 				arnValue.AccountID = accountId
+				if arnValue.String() == strValue {
+					changes = append(changes, fmt.Sprintf("kept %s, already in the partition, region and account of the deployment", strValue))
+					continue
+				}
 				data.Set(key, arnValue.String())
+				changes = append(changes, fmt.Sprintf("relocated %s to %s", strValue, arnValue.String()))
 			}
 			continue
 		}
+		var nestedChanges []string
+		var err error
 		if nestedMap, ok := value.(orderedmap.OrderedMap); ok {
 			// Recursively call for nested ordered maps
-			UpdateResourcesARN(&nestedMap, region, accountId, connectInstanceArn, promptArnMap, lambdaFunctionsArnMap, displayNameMap)
+			nestedChanges, err = UpdateResourcesARN(&nestedMap, partition, region, accountId, relocate)
 		} else if nestedSlice, ok := value.([]interface{}); ok {
 			for _, item := range nestedSlice {
 				if itemMap, ok := item.(orderedmap.OrderedMap); ok {
 					// Recursively call for each map in the slice
-					UpdateResourcesARN(&itemMap, region, accountId, connectInstanceArn, promptArnMap, lambdaFunctionsArnMap, displayNameMap)
+					var itemChanges []string
+					itemChanges, err = UpdateResourcesARN(&itemMap, partition, region, accountId, relocate)
+					if err != nil {
+						break
+					}
+					nestedChanges = append(nestedChanges, itemChanges...)
 				}
 			}
 		}
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, nestedChanges...)
 	}
+	return changes, nil
 }
 
 //	{
@@ -80,83 +93,426 @@ func UpdateResourcesARN(data *orderedmap.OrderedMap, region, accountId, connectI
 //		  ]
 //		}
 //	  }
-func UpdateExtractOutputVariables(data *orderedmap.OrderedMap, outputVariablesToAttributesMap map[string]string) {
+func UpdateExtractOutputVariables(data *orderedmap.OrderedMap, outputVariablesToAttributesMap map[string]string) ([]string, error) {
+	action, ok, err := findAction(data, "ExtractOutputVariables")
+	if err != nil || !ok {
+		return nil, err
+	}
+	parameters, ok, err := objectField(action, "Parameters")
+	if err != nil || !ok {
+		return nil, actionError("ExtractOutputVariables", err)
+	}
+	attributes, ok, err := objectField(parameters, "Attributes")
+	if err != nil || !ok {
+		return nil, actionError("ExtractOutputVariables", err)
+	}
+
+	// Sorted so the module content does not change between synths
+	changes := []string{}
+	for _, outputVariable := range slices.Sorted(maps.Keys(outputVariablesToAttributesMap)) {
+		targetAttribute := outputVariablesToAttributesMap[outputVariable]
+		attributes.Set(targetAttribute, fmt.Sprintf("$.External.outputVariables.%s", outputVariable))
+		changes = append(changes, fmt.Sprintf("set attribute %s to output variable %s", targetAttribute, outputVariable))
+	}
+	parameters.Set("Attributes", attributes)
+	action.Set("Parameters", parameters)
+	return changes, nil
+}
 
-	actions, ok := data.Get("Actions")
+//	{
+//		"Parameters": {
+//		  "Text": "$.External.text"
+//		},
+//		"Identifier": "SpeakResponse",
+//		"Type": "MessageParticipant",
+//		"Transitions": {
+//		  "NextAction": "PullAction",
+//		  "Errors": [
+//			{
+//			  "NextAction": "SetErrorMessage3",
+//			  "ErrorType": "NoMatchingError"
+//			}
+//		  ]
+//		}
+//	 }
+func UpdateSpeakResponseToSSML(data *orderedmap.OrderedMap) ([]string, error) {
+	action, ok, err := findAction(data, "SpeakResponse")
+	if err != nil || !ok {
+		return nil, err
+	}
+	parameters, ok, err := objectField(action, "Parameters")
+	if err != nil || !ok {
+		return nil, actionError("SpeakResponse", err)
+	}
+	text, ok := parameters.Get("Text")
 	if !ok {
-		return
+		return nil, nil
 	}
+	parameters.Set("SSML", text)
+	parameters.Delete("Text")
 
-	for _, val := range actions.([]any) {
-		action := val.(orderedmap.OrderedMap)
-		identifier, ok := action.Get("Identifier")
-		if !ok || identifier.(string) != "ExtractOutputVariables" {
-			continue
+	action.Set("Parameters", parameters)
+	return []string{"SpeakResponse speaks its text as SSML"}, nil
+}
+
+//	{
+//		"Parameters": {
+//		  "QueueId": "arn:aws:connect:region:account:instance/instance-id/queue/queue-id"
+//		},
+//		"Identifier": "SetTransferToAgentQueue1",
+//		"Type": "UpdateContactTargetQueue",
+//		"Transitions": {
+//		  "NextAction": "DidModuleStartMediaStreaming2",
+//		  "Errors": [
+//			{
+//			  "NextAction": "DidModuleStartMediaStreaming2",
+//			  "ErrorType": "NoMatchingError"
+//			}
+//		  ]
+//		}
+//	 }
+//
+// UpdateTransferToAgentQueue routes SetDispositionTransferToAgent through UpdateContactTargetQueue blocks, so the
+// flow invoking the module can transfer the contact to the queue that was set. When outputVariable is set, a
+// Compare block picks the queue from queueArns by the value of that GenerativeAgent output variable, falling back
+// to defaultQueueArn. Without a matching queue the target queue is left unchanged.
+func UpdateTransferToAgentQueue(data *orderedmap.OrderedMap, outputVariable string, queueArns map[string]string, defaultQueueArn string) ([]string, error) {
+	disposition, ok, err := findAction(data, "SetDispositionTransferToAgent")
+	if err != nil || !ok {
+		return nil, err
+	}
+	transitions, ok, err := objectField(disposition, "Transitions")
+	if err != nil || !ok {
+		return nil, actionError("SetDispositionTransferToAgent", err)
+	}
+	exitAction, ok, err := stringField(transitions, "NextAction")
+	if err != nil || !ok {
+		return nil, actionError("SetDispositionTransferToAgent", err)
+	}
+	x, y, err := actionPosition(data, "SetDispositionTransferToAgent")
+	if err != nil {
+		return nil, err
+	}
+
+	// One UpdateContactTargetQueue block per distinct queue
+	changes := []string{}
+	queueActions := map[string]string{}
+	addQueueAction := func(queueArn string) (string, error) {
+		if identifier, exists := queueActions[queueArn]; exists {
+			return identifier, nil
 		}
+		identifier := fmt.Sprintf("SetTransferToAgentQueue%d", len(queueActions)+1)
+		queueActions[queueArn] = identifier
+		changes = append(changes, fmt.Sprintf("added %s setting the target queue to %s", identifier, queueArn))
+		return identifier, addAction(data, newFlowAction(identifier, "UpdateContactTargetQueue",
+			newOrderedMap("QueueId", queueArn),
+			newFlowTransitions(exitAction, flowError{"NoMatchingError", exitAction}),
+		), newActionMetadata(x+480, y-240-float64(len(queueActions))*180))
+	}
 
-		parameters, ok := action.Get("Parameters")
-		if !ok {
-			break
+	entryAction := exitAction
+	if defaultQueueArn != "" {
+		if entryAction, err = addQueueAction(defaultQueueArn); err != nil {
+			return nil, err
 		}
-		parametersMap := parameters.(orderedmap.OrderedMap)
-		attributes, ok := parametersMap.Get("Attributes")
-		if !ok {
-			break
+	}
+	if outputVariable != "" && len(queueArns) > 0 {
+		values := make([]string, 0, len(queueArns))
+		for value := range queueArns {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		conditions := []flowCondition{}
+		for _, value := range values {
+			identifier, err := addQueueAction(queueArns[value])
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, flowCondition{value, identifier})
 		}
-		attributesMap := attributes.(orderedmap.OrderedMap)
-		for outputVariable, targetAttribute := range outputVariablesToAttributesMap {
-			attributesMap.Set(targetAttribute, fmt.Sprintf("$.External.outputVariables.%s", outputVariable))
+		err := addAction(data, newFlowAction("RouteTransferToAgentQueue", "Compare",
+			newOrderedMap("ComparisonValue", fmt.Sprintf("$.External.outputVariables.%s", outputVariable)),
+			newCompareTransitions(entryAction, conditions),
+		), newActionMetadata(x+240, y-240))
+		if err != nil {
+			return nil, err
 		}
-		parametersMap.Set("Attributes", attributesMap)
-		action.Set("Parameters", parametersMap)
-		break
+		changes = append(changes, fmt.Sprintf("added RouteTransferToAgentQueue picking the queue by output variable %s", outputVariable))
+		entryAction = "RouteTransferToAgentQueue"
+	}
 
+	if err := redirectTransitions(disposition, exitAction, entryAction); err != nil {
+		return nil, actionError("SetDispositionTransferToAgent", err)
 	}
+	changes = append(changes, fmt.Sprintf("SetDispositionTransferToAgent continues to %s", entryAction))
+	return changes, nil
 }
 
 //	{
 //		"Parameters": {
-//		  "Text": "$.External.text"
+//		  "TextToSpeechVoice": "Joanna",
+//		  "TextToSpeechEngine": "Neural"
 //		},
-//		"Identifier": "SpeakResponse",
-//		"Type": "MessageParticipant",
+//		"Identifier": "SetTextToSpeechVoice",
+//		"Type": "UpdateContactTextToSpeechVoice",
 //		"Transitions": {
-//		  "NextAction": "PullAction",
+//		  "NextAction": "Engage",
 //		  "Errors": [
 //			{
-//			  "NextAction": "SetErrorMessage3",
+//			  "NextAction": "Engage",
 //			  "ErrorType": "NoMatchingError"
 //			}
 //		  ]
 //		}
 //	 }
-func UpdateSpeakResponseToSSML(data *orderedmap.OrderedMap) {
-	actions, ok := data.Get("Actions")
+//
+// UpdateTextToSpeechVoice sets the voice, and the language of the contact when language is not empty, before the
+// Engage block, so the module no longer depends on the voice set by the invoking flow. The blocks are inserted
+// before Engage rather than before the first SpeakResponse: every SpeakResponse happens after Engage, while
+// SpeakResponse is reached from the Compare block of every turn, where the voice would be set again each time.
+// engine is standard, neural or generative.
+func UpdateTextToSpeechVoice(data *orderedmap.OrderedMap, voice, engine, language string) ([]string, error) {
+	flowEngine, ok := textToSpeechEngines[engine]
 	if !ok {
-		return
+		return nil, fmt.Errorf("text to speech engine %q is not valid, expected %s", engine, strings.Join(slices.Sorted(maps.Keys(textToSpeechEngines)), ", "))
+	}
+	if _, ok, err := findAction(data, "Engage"); err != nil || !ok {
+		return nil, err
+	}
+	x, y, err := actionPosition(data, "Engage")
+	if err != nil {
+		return nil, err
+	}
+	if err := redirectAllTransitions(data, "Engage", "SetTextToSpeechVoice"); err != nil {
+		return nil, err
 	}
 
-	for _, val := range actions.([]any) {
-		action := val.(orderedmap.OrderedMap)
-		identifier, ok := action.Get("Identifier")
-		if !ok || identifier.(string) != "SpeakResponse" {
-			continue
+	changes := []string{}
+	voiceNextAction := "Engage"
+	if language != "" {
+		voiceNextAction = "SetLanguage"
+		err := addAction(data, newFlowAction("SetLanguage", "UpdateContactData",
+			newOrderedMap("LanguageCode", language),
+			newFlowTransitions("Engage", flowError{"NoMatchingError", "Engage"}),
+		), newActionMetadata(x, y+420))
+		if err != nil {
+			return nil, err
 		}
+		changes = append(changes, fmt.Sprintf("added SetLanguage setting the language to %s before Engage", language))
+	}
+	err = addAction(data, newFlowAction("SetTextToSpeechVoice", "UpdateContactTextToSpeechVoice",
+		newOrderedMap(
+			"TextToSpeechVoice", voice,
+			"TextToSpeechEngine", flowEngine,
+		),
+		newFlowTransitions(voiceNextAction, flowError{"NoMatchingError", voiceNextAction}),
+	), newActionMetadata(x-240, y+420))
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, fmt.Sprintf("added SetTextToSpeechVoice setting the %s voice with the %s engine before Engage", voice, engine))
+	return changes, nil
+}
+
+// textToSpeechEngines maps the engines of the config to their name in the Amazon Connect flow language
+var textToSpeechEngines = map[string]string{
+	config.TextToSpeechEngineStandard:   "Standard",
+	config.TextToSpeechEngineNeural:     "Neural",
+	config.TextToSpeechEngineGenerative: "Generative",
+}
 
-		parameters, ok := action.Get("Parameters")
+// UpdateModuleName passes the name of the module to the Engage and PullAction functions as the moduleName
+// parameter, so they use the input variable mappings and SSML conversions configured for the module.
+func UpdateModuleName(data *orderedmap.OrderedMap, moduleName string) ([]string, error) {
+	changes := []string{}
+	for _, identifier := range []string{"Engage", "PullAction"} {
+		action, ok, err := findAction(data, identifier)
+		if err != nil {
+			return nil, err
+		}
 		if !ok {
-			break
+			continue
+		}
+		parameters, ok, err := objectField(action, "Parameters")
+		if err != nil || !ok {
+			return nil, actionError(identifier, err)
+		}
+		attributes, ok, err := objectField(parameters, "LambdaInvocationAttributes")
+		if err != nil {
+			return nil, actionError(identifier, err)
 		}
-		parametersMap := parameters.(orderedmap.OrderedMap)
-		text, ok := parametersMap.Get("Text")
 		if !ok {
-			break
+			attributes = newOrderedMap()
 		}
-		parametersMap.Set("SSML", text)
-		parametersMap.Delete("Text")
+		attributes.Set("moduleName", moduleName)
+		parameters.Set("LambdaInvocationAttributes", attributes)
+		action.Set("Parameters", parameters)
+		changes = append(changes, fmt.Sprintf("passed moduleName %s to %s", moduleName, identifier))
+	}
+	return changes, nil
+}
+
+// arnResourceType returns the type of the resource of an ARN, e.g. function for a Lambda function, or an empty string
+// for services without resource types such as SQS. Amazon Connect resources are named by their type within the
+// instance, e.g. queue for arn:aws:connect:region:account:instance/instance-id/queue/queue-id.
+func arnResourceType(resourceArn arn.ARN) string {
+	if !strings.ContainsAny(resourceArn.Resource, "/:") {
+		return ""
+	}
+	sections := strings.FieldsFunc(resourceArn.Resource, func(r rune) bool { return r == '/' || r == ':' })
+	if len(sections) == 0 {
+		return ""
+	}
+	if resourceArn.Service == "connect" && sections[0] == "instance" && len(sections) >= 3 {
+		return sections[2]
+	}
+	return sections[0]
+}
+
+// actionError reports an action that does not have the expected shape, err is nil when a field is missing
+func actionError(identifier string, err error) error {
+	if err == nil {
+		return fmt.Errorf("action %s does not have the fields of the flow module template", identifier)
+	}
+	return fmt.Errorf("action %s: %w", identifier, err)
+}
+
+// findAction returns the action with the given Identifier
+func findAction(data *orderedmap.OrderedMap, identifier string) (orderedmap.OrderedMap, bool, error) {
+	actions, _, err := objectListField(*data, "Actions")
+	if err != nil {
+		return orderedmap.OrderedMap{}, false, err
+	}
+	for i, action := range actions {
+		id, _, err := stringField(action, "Identifier")
+		if err != nil {
+			return orderedmap.OrderedMap{}, false, fmt.Errorf("Actions[%d]: %w", i, err)
+		}
+		if id == identifier {
+			return action, true, nil
+		}
+	}
+	return orderedmap.OrderedMap{}, false, nil
+}
 
-		action.Set("Parameters", parametersMap)
-		break
+// setParameter sets a parameter of action, adding the Parameters object when the action has none
+func setParameter(action *orderedmap.OrderedMap, name string, value any) error {
+	parameters, ok, err := objectField(*action, "Parameters")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		parameters = newOrderedMap()
+	}
+	parameters.Set(name, value)
+	action.Set("Parameters", parameters)
+	return nil
+}
+
+// addAction appends an action to the module along with its console metadata
+func addAction(data *orderedmap.OrderedMap, action, metadata orderedmap.OrderedMap) error {
+	actions, _ := data.Get("Actions")
+	actionsList, ok := actions.([]any)
+	if actions != nil && !ok {
+		return fmt.Errorf("Actions is %s, expected a list", jsonTypeName(actions))
+	}
+	data.Set("Actions", append(actionsList, action))
+
+	identifier, _, err := stringField(action, "Identifier")
+	if err != nil {
+		return err
+	}
+	moduleMetadata, ok, err := objectField(*data, "Metadata")
+	if err != nil || !ok {
+		return err
+	}
+	actionMetadata, ok, err := objectField(moduleMetadata, "ActionMetadata")
+	if err != nil || !ok {
+		return metadataError(err)
+	}
+	actionMetadata.Set(identifier, metadata)
+	moduleMetadata.Set("ActionMetadata", actionMetadata)
+	data.Set("Metadata", moduleMetadata)
+	return nil
+}
+
+// actionPosition returns the console position of an action, used to place new actions next to it
+func actionPosition(data *orderedmap.OrderedMap, identifier string) (float64, float64, error) {
+	moduleMetadata, ok, err := objectField(*data, "Metadata")
+	if err != nil || !ok {
+		return 0, 0, err
+	}
+	actionMetadata, ok, err := objectField(moduleMetadata, "ActionMetadata")
+	if err != nil || !ok {
+		return 0, 0, metadataError(err)
+	}
+	metadata, ok, err := objectField(actionMetadata, identifier)
+	if err != nil || !ok {
+		return 0, 0, metadataError(err)
+	}
+	position, ok, err := objectField(metadata, "position")
+	if err != nil || !ok {
+		return 0, 0, metadataError(err)
+	}
+	x, _ := position.Get("x")
+	y, _ := position.Get("y")
+	xValue, _ := x.(float64)
+	yValue, _ := y.(float64)
+	return xValue, yValue, nil
+}
+
+func metadataError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("Metadata: %w", err)
+}
+
+// redirectAllTransitions points the start action and every transition of the module that targets from to to
+func redirectAllTransitions(data *orderedmap.OrderedMap, from, to string) error {
+	startAction, _, err := stringField(*data, "StartAction")
+	if err != nil {
+		return err
+	}
+	if startAction == from {
+		data.Set("StartAction", to)
+	}
+	actions, _, err := objectListField(*data, "Actions")
+	if err != nil {
+		return err
+	}
+	for i, action := range actions {
+		if err := redirectTransitions(action, from, to); err != nil {
+			return fmt.Errorf("Actions[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
 
+// redirectTransitions points every transition of action that targets from, including conditions and error
+// branches, to to
+func redirectTransitions(action orderedmap.OrderedMap, from, to string) error {
+	transitions, ok, err := objectField(action, "Transitions")
+	if err != nil || !ok {
+		return err
+	}
+	if next, _, err := stringField(transitions, "NextAction"); err != nil {
+		return err
+	} else if next == from {
+		transitions.Set("NextAction", to)
+	}
+	for _, branches := range []string{"Conditions", "Errors"} {
+		branchesList, _, err := objectListField(transitions, branches)
+		if err != nil {
+			return err
+		}
+		for _, branch := range branchesList {
+			if next, _, err := stringField(branch, "NextAction"); err != nil {
+				return err
+			} else if next == from {
+				branch.Set("NextAction", to)
+			}
+		}
 	}
+	return nil
 }
@@ -0,0 +1,178 @@
+package quickstart
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/iancoleman/orderedmap"
+)
+
+// flowReferencePattern matches the references of a parameter to the result of a Lambda function and to flow
+// attributes, e.g. $.External.text or $.FlowAttributes.ASAPP_Started_Media
+var flowReferencePattern = regexp.MustCompile(`\$\.(External|FlowAttributes)(\.[A-Za-z0-9_-]+)?`)
+
+// ValidateModule checks a Contact Flow Module before it is deployed: the start action and every transition,
+// including condition and error branches, must target an existing action, every action must be reachable from the
+// start action, the console metadata must only describe existing actions, and the $.External and $.FlowAttributes
+// values an action refers to must be set on every path leading to it. It returns all the problems found.
+func ValidateModule(data *orderedmap.OrderedMap) error {
+	g, err := buildFlowGraph(data)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if g.startAction == "" {
+		errs = append(errs, fmt.Errorf("StartAction is missing"))
+	} else if _, ok := g.action(g.startAction); !ok {
+		errs = append(errs, fmt.Errorf("StartAction %s is not an action of the module", g.startAction))
+	}
+	for _, action := range g.actions {
+		for _, edge := range action.edges {
+			if _, ok := g.action(edge.target); !ok {
+				errs = append(errs, fmt.Errorf("action %s: %s targets %s, which is not an action of the module", action.identifier, edge.describe(), edge.target))
+			}
+		}
+	}
+
+	// A broken transition usually leaves the rest of the module unreachable, so unreachable actions are only
+	// reported when all transitions are valid
+	reached := g.reachable()
+	transitionsValid := len(errs) == 0
+	for _, action := range g.actions {
+		if transitionsValid && !reached[action.identifier] {
+			errs = append(errs, fmt.Errorf("action %s is not reachable from StartAction", action.identifier))
+		}
+	}
+	for _, identifier := range g.actionMetadata.Keys() {
+		if _, ok := g.action(identifier); !ok {
+			errs = append(errs, fmt.Errorf("Metadata.ActionMetadata describes %s, which is not an action of the module", identifier))
+		}
+	}
+
+	setOnEntry := g.setOnEntry(reached)
+	for _, action := range g.actions {
+		if !reached[action.identifier] {
+			continue
+		}
+		reported := map[string]bool{}
+		for _, value := range parameterStrings(action.parameters) {
+			for _, match := range flowReferencePattern.FindAllStringSubmatch(value, -1) {
+				required := match[1] + match[2]
+				if match[1] == "External" {
+					required = "External"
+				} else if match[2] == "" {
+					continue
+				}
+				if !setOnEntry[action.identifier][required] && !reported[match[0]] {
+					reported[match[0]] = true
+					errs = append(errs, fmt.Errorf("action %s refers to %s, which is not set on every path from StartAction", action.identifier, match[0]))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e flowEdge) describe() string {
+	switch {
+	case e.isError:
+		return fmt.Sprintf("error branch %s", e.branch)
+	case e.branch != "":
+		return fmt.Sprintf("condition %q", e.branch)
+	default:
+		return "NextAction"
+	}
+}
+
+// sets returns the values an action sets when it succeeds: External for Lambda functions, and
+// FlowAttributes.<name> for each flow attribute it sets
+func (a flowAction) sets() []string {
+	switch a.actionType {
+	case "InvokeLambdaFunction":
+		return []string{"External"}
+	case "UpdateFlowAttributes":
+		attributes, _, _ := objectField(a.parameters, "FlowAttributes")
+		values := []string{}
+		for _, name := range attributes.Keys() {
+			values = append(values, "FlowAttributes."+name)
+		}
+		return values
+	}
+	return nil
+}
+
+// setOnEntry returns, for each reachable action, the values set on every path from the start action to it. Error
+// branches are taken without the values of the failed action.
+func (g *flowGraph) setOnEntry(reached map[string]bool) map[string]map[string]bool {
+	all := map[string]bool{}
+	for _, action := range g.actions {
+		for _, value := range action.sets() {
+			all[value] = true
+		}
+	}
+	entry := map[string]map[string]bool{}
+	for identifier := range reached {
+		entry[identifier] = map[string]bool{}
+		if identifier != g.startAction {
+			for value := range all {
+				entry[identifier][value] = true
+			}
+		}
+	}
+
+	// Narrow the values down until every action only keeps the ones set by all of its predecessors
+	for changed := true; changed; {
+		changed = false
+		for _, action := range g.actions {
+			if !reached[action.identifier] {
+				continue
+			}
+			exit := map[string]bool{}
+			for value := range entry[action.identifier] {
+				exit[value] = true
+			}
+			for _, edge := range action.edges {
+				target, ok := entry[edge.target]
+				if !ok {
+					continue
+				}
+				for value := range target {
+					if exit[value] {
+						continue
+					}
+					if !edge.isError && slices.Contains(action.sets(), value) {
+						continue
+					}
+					delete(target, value)
+					changed = true
+				}
+			}
+		}
+	}
+	return entry
+}
+
+// parameterStrings returns the strings held by parameters, at any depth
+func parameterStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case orderedmap.OrderedMap:
+		strs := []string{}
+		for _, key := range v.Keys() {
+			nested, _ := v.Get(key)
+			strs = append(strs, parameterStrings(nested)...)
+		}
+		return strs
+	case []any:
+		strs := []string{}
+		for _, item := range v {
+			strs = append(strs, parameterStrings(item)...)
+		}
+		return strs
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// Reads the runtime configuration of the function from AWS AppConfig through the AppConfig Lambda extension.
+// The extension is only attached, and ASAPP_APPCONFIG_PATH set, when the CDK stack enables appConfig; otherwise, or
+// if AppConfig cannot be reached, the configuration bundled with the function is used.
+const appConfigPath = process.env['ASAPP_APPCONFIG_PATH'];
+const appConfigPort = process.env['AWS_APPCONFIG_EXTENSION_HTTP_PORT'] || '2772';
+
+/**
+ * Returns the value of key in the AppConfig configuration, or bundledValue when it is not available.
+ * @template T
+ * @param {string} key
+ * @param {T} bundledValue
+ * @returns {Promise<T>}
+ */
+export async function getRuntimeConfig(key, bundledValue) {
+    if (!appConfigPath) {
+        return bundledValue;
+    }
+    try {
+        const response = await fetch(`http://localhost:${appConfigPort}${appConfigPath}`);
+        if (!response.ok) {
+            throw new Error(`AppConfig extension returned status ${response.status}`);
+        }
+        const config = await response.json();
+        if (config[key] === undefined) {
+            return bundledValue;
+        }
+        return config[key];
+    } catch (err) {
+        console.error(`Failed to read ${key} from AppConfig, using the bundled configuration`, err);
+        return bundledValue;
+    }
+}
+
+/**
+ * Returns the value of key for the Contact Flow Module named moduleName, read from the modules of the AppConfig
+ * configuration or bundledModules. The value of key for the deployment is returned for invocations without a module
+ * name, or from a module that is not configured.
+ * @template T
+ * @param {string | undefined} moduleName
+ * @param {string} key
+ * @param {Record<string, Record<string, any>>} bundledModules
+ * @param {T} bundledValue
+ * @returns {Promise<T>}
+ */
+export async function getModuleConfig(moduleName, key, bundledModules, bundledValue) {
+    if (moduleName) {
+        const modules = await getRuntimeConfig('modules', bundledModules);
+        if (modules[moduleName]?.[key] !== undefined) {
+            return modules[moduleName][key];
+        }
+        console.log(`No ${key} configured for module ${moduleName}, using the deployment configuration`);
+    }
+    return getRuntimeConfig(key, bundledValue);
+}
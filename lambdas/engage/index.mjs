@@ -1,5 +1,8 @@
 import { default as axios } from 'axios';
-import { default as attributesToInputVariables } from './attributesToInputVariables.mjs';
+import { default as bundledAttributesToInputVariables } from './attributesToInputVariables.mjs';
+import { default as bundledInputVariables } from './inputVariables.mjs';
+import { default as bundledModules } from './modules.mjs';
+import { getModuleConfig } from './appConfig.mjs';
 
 /*
 {
@@ -59,6 +62,105 @@ import { default as attributesToInputVariables } from './attributesToInputVariab
 */
 
 
+/**
+ * Returns the language of the engage request: ASAPP_LANGUAGE, or the language of the contact when it is 'contact'
+ * @param {import("@types/aws-lambda").ConnectContactFlowEvent["Details"]["ContactData"]} contactData
+ * @returns {string}
+ */
+function engageLanguage(contactData) {
+    const language = process.env['ASAPP_LANGUAGE'] || 'en-US';
+    if (language !== 'contact') {
+        return language;
+    }
+    return contactData.LanguageCode || process.env['ASAPP_FALLBACK_LANGUAGE'] || 'en-US';
+}
+
+/**
+ * Returns the customer ID of the engage request, read from ASAPP_CUSTOMER_ID_SOURCE, a dot separated path under the
+ * event details. The customer phone number is used when the source has no value.
+ * @param {import("@types/aws-lambda").ConnectContactFlowEvent["Details"]} details
+ * @returns {string}
+ */
+function engageCustomerId(details) {
+    const source = process.env['ASAPP_CUSTOMER_ID_SOURCE'] || 'ContactData.CustomerEndpoint.Address';
+    const customerId = source.split('.').reduce((obj, key) => (obj === undefined || obj === null) ? undefined : obj[key], details);
+    if (customerId === undefined || customerId === null || customerId === '') {
+        console.log(`No customer ID at ${source}, using the customer endpoint address`);
+        return details.ContactData.CustomerEndpoint?.Address;
+    }
+    return String(customerId);
+}
+
+/**
+ * Reads the value of an input variable mapping from the event details, returning undefined when it has no value
+ * @param {import("@types/aws-lambda").ConnectContactFlowEvent["Details"]} details
+ * @param {import("./types").InputVariableMapping} mapping
+ * @returns {string | number | boolean | undefined}
+ */
+function mapInputVariable(details, mapping) {
+    let value = mapping.source.split('.').reduce((obj, key) => (obj === undefined || obj === null) ? undefined : obj[key], details);
+    if (value !== undefined && value !== null && value !== '') {
+        value = coerceInputVariable(transformInputVariable(String(value), mapping.transform), mapping.type);
+    } else {
+        value = undefined;
+    }
+    if (value === undefined && mapping.default !== undefined && mapping.default !== null) {
+        value = coerceInputVariable(mapping.default, mapping.type);
+    }
+    return value;
+}
+
+/**
+ * @param {string} value
+ * @param {string} transform
+ * @returns {string | undefined}
+ */
+function transformInputVariable(value, transform) {
+    switch (transform) {
+        case 'lowercase':
+            return value.toLowerCase();
+        case 'uppercase':
+            return value.toUpperCase();
+        case 'trim':
+            return value.trim();
+        case 'e164': {
+            // Drop formatting characters and the 00 international prefix, values that are not E.164 numbers are missing
+            let number = value.replace(/[\s().-]/g, '');
+            if (number.startsWith('00')) {
+                number = '+' + number.substring(2);
+            }
+            return /^\+[1-9][0-9]{1,14}$/.test(number) ? number : undefined;
+        }
+        default:
+            return value;
+    }
+}
+
+/**
+ * @param {string | undefined} value
+ * @param {string} type
+ * @returns {string | number | boolean | undefined}
+ */
+function coerceInputVariable(value, type) {
+    if (value === undefined) {
+        return undefined;
+    }
+    switch (type) {
+        case 'number': {
+            const number = Number(value);
+            return value.trim() === '' || Number.isNaN(number) ? undefined : number;
+        }
+        case 'boolean': {
+            const normalized = value.trim().toLowerCase();
+            if (['true', 'yes', '1'].includes(normalized)) return true;
+            if (['false', 'no', '0'].includes(normalized)) return false;
+            return undefined;
+        }
+        default:
+            return value;
+    }
+}
+
 /**
  * 
  * @param {import("@types/aws-lambda").ConnectContactFlowEvent} event 
@@ -69,7 +171,11 @@ export const handler = async (event) => {
 
     console.log(`Executing for guid - ${event.Details.ContactData.ContactId}`);
 
+    // Named Contact Flow Modules pass their name to select their own mappings
+    const moduleName = event.Details.Parameters?.moduleName;
+
     const inputVariables = {};
+    const attributesToInputVariables = await getModuleConfig(moduleName, 'attributesToInputVariablesMap', bundledModules, bundledAttributesToInputVariables);
     // Map Amazon Connect User Defined Attributes to input variables for use in Engage flows.
     if (event.Details.ContactData.Attributes) {
         for (const [key, value] of Object.entries(event.Details.ContactData.Attributes)) {
@@ -79,15 +185,32 @@ export const handler = async (event) => {
         }
     }
 
+    // Map values of the event to input variables as described in inputVariables.mjs, they take precedence over the attributes map
+    const inputVariableMappings = await getModuleConfig(moduleName, 'inputVariables', bundledModules, bundledInputVariables);
+    for (const mapping of inputVariableMappings) {
+        const value = mapInputVariable(event.Details, mapping);
+        if (value !== undefined) {
+            inputVariables[mapping.target] = value;
+        } else if (mapping.required) {
+            console.log(`Required input variable ${mapping.target} has no value at ${mapping.source}`);
+            return {
+                result: "error",
+                asappStatusCode: 0,
+                asappErrorResponse: null,
+                errorMessage: `Required input variable ${mapping.target} has no value at ${mapping.source}`
+            };
+        }
+    }
+
 
     /**
      * @type {import("./types").EngageRequest }
      */
     const req = {
-        namespace: 'amazonconnect',
+        namespace: process.env['ASAPP_NAMESPACE'] || 'amazonconnect',
         guid: event.Details.ContactData.ContactId,
-        language: 'en-US',
-        customerId: event.Details.ContactData.CustomerEndpoint.Address,
+        language: engageLanguage(event.Details.ContactData),
+        customerId: engageCustomerId(event.Details),
         inputVariables,
         amazonConnectParams: {
             streamArn: event.Details.ContactData.MediaStreams.Customer.Audio.StreamARN
@@ -0,0 +1,4 @@
+// Maps values of the Amazon Connect Lambda event to input variables for use in Engage flows. Each mapping reads source, a dot separated path under event.Details,
+// applies transform (lowercase, uppercase, trim or e164) and converts the value to type (string, number or boolean). When the value is missing, default is used;
+// if there is no default and required is true, the engage request is not sent and an error is returned to the flow.
+export default [];
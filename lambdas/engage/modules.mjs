@@ -0,0 +1,3 @@
+// Configuration of the named Contact Flow Modules of the deployment, keyed by module name. A module passes its name to the function as the moduleName parameter,
+// and the function uses the ssmlConversions, attributesToInputVariablesMap and inputVariables of that module instead of the ones of the deployment.
+export default {};
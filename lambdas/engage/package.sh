@@ -1,3 +1,3 @@
 #!/bin/zsh
 npm install
-zip -X -r lambda.zip node_modules index.mjs types.d.ts attributesToInputVariables.mjs
\ No newline at end of file
+zip -X -r lambda.zip node_modules index.mjs appConfig.mjs types.d.ts attributesToInputVariables.mjs inputVariables.mjs modules.mjs
\ No newline at end of file
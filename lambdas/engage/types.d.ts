@@ -1,13 +1,22 @@
 export type EngageRequest = {
-    namespace: 'amazonconnect'
+    namespace: string
     guid: string 
-    language: 'en-US'
+    language: string
     customerId: string
     amazonConnectParams: AmazonConnectParams
-    inputVariables: Record<string, string>
+    inputVariables: Record<string, string | number | boolean>
 
 }
 
+export type InputVariableMapping = {
+    source: string
+    target: string
+    default?: string | null
+    required?: boolean
+    transform?: '' | 'lowercase' | 'uppercase' | 'trim' | 'e164'
+    type?: '' | 'string' | 'number' | 'boolean'
+}
+
 export type AmazonConnectParams = {
     streamArn: string
 }
@@ -1,6 +1,8 @@
 import { GlideClient, Transaction } from "@valkey/valkey-glide";
 
-import {default as ssmlConversions} from './ssmlConversions.mjs';
+import {default as bundledSSMLConversions} from './ssmlConversions.mjs';
+import {default as bundledModules} from './modules.mjs';
+import { getModuleConfig } from './appConfig.mjs';
 const valkeyTTLSeconds = 21600;
 /*
 {
@@ -150,7 +152,7 @@ export const handler = async (event) => {
         switch (nextAction.action) {
             case 'speak':
                 response.next = nextAction.action;
-                response.text = ssmlConvert(nextAction.speakParams.text);
+                response.text = ssmlConvert(nextAction.speakParams.text, await getModuleConfig(event.Details.Parameters.moduleName, 'ssmlConversions', bundledModules, bundledSSMLConversions));
                 return response
             case 'transferToAgent':
             case 'transferToSystem':
@@ -181,9 +183,10 @@ export const handler = async (event) => {
 };
 
 
-function ssmlConvert(text) {
+function ssmlConvert(text, ssmlConversions) {
     let ssmlText = text;
-    let convertToSSML = false;
+    // When SSML conversions can change at runtime the flow module always speaks SSML
+    let convertToSSML = process.env['ASAPP_SSML_ALWAYS'] === 'true';
     let ret = text;
     if (ssmlConversions && ssmlConversions.length > 0) {
         convertToSSML = true;
@@ -191,7 +194,8 @@ function ssmlConvert(text) {
         for (const conversion of ssmlConversions) {
             const searchFor = RegExp(conversion.searchFor, 'gi');
             if (ssmlText.match(searchFor)) {
-                ssmlText = ssmlText.replaceAll(searchFor, conversion.replaceWith);
+                const replaceWith = conversion.replaceGroups ? groupReplacer(conversion) : conversion.replaceWith;
+                ssmlText = ssmlText.replaceAll(searchFor, replaceWith);
             }
         }
     }
@@ -201,4 +205,21 @@ function ssmlConvert(text) {
     }
 
     return ret;
+}
+
+// Replaces a match with the replacement of the named group of searchFor that took part in it, or replaceWith,
+// so all lexemes of the lexicons are applied in one pass. Only $& and $$ are expanded.
+function groupReplacer(conversion) {
+    return (...args) => {
+        const match = args[0];
+        const groups = args[args.length - 1];
+        let replaceWith = conversion.replaceWith;
+        for (const [name, replacement] of Object.entries(conversion.replaceGroups)) {
+            if (groups?.[name] !== undefined) {
+                replaceWith = replacement;
+                break;
+            }
+        }
+        return replaceWith.replace(/\$[$&]/g, (token) => token === '$$' ? '$' : match);
+    };
 }
\ No newline at end of file
@@ -11,4 +11,4 @@ if [ ! -d "node_modules" ]; then
     exit 1
 fi
 
-zip -X -r lambda.zip node_modules index.mjs types.d.ts ssmlConversions.mjs
\ No newline at end of file
+zip -X -r lambda.zip node_modules index.mjs appConfig.mjs types.d.ts ssmlConversions.mjs modules.mjs
\ No newline at end of file